@@ -0,0 +1,264 @@
+package xlsxt
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	typeOfCellValue = reflect.TypeOf((*cellValue)(nil))
+)
+
+// builtinHelperMap 保存内置 helper，同名时用户注册的 helper 优先。
+var builtinHelperMap = make(map[string]*helper)
+
+func init() {
+	registerBuiltinHelper("link", link)
+	registerBuiltinHelper("comment", comment)
+	registerBuiltinHelper("dropdown", dropdown)
+	registerBuiltinHelper("style", style)
+	registerBuiltinHelper("sum", aggSum)
+	registerBuiltinHelper("count", aggCount)
+	registerBuiltinHelper("avg", aggAvg)
+	registerBuiltinHelper("min", aggMin)
+	registerBuiltinHelper("max", aggMax)
+}
+
+func registerBuiltinHelper(key string, f interface{}) {
+	h, err := new(reflect.ValueOf(f))
+	if err != nil {
+		panic(fmt.Sprintf("builtin helper `%s`: %v", key, err))
+	}
+	builtinHelperMap[key] = h
+}
+
+func getHelper(key string) (*helper, bool) {
+	if h, in := helperMap[key]; in {
+		return h, true
+	}
+	h, in := builtinHelperMap[key]
+	return h, in
+}
+
+// cellValue is the result of builtin helpers, it carries the cell value and
+// extra cell attributes like hyperlink. Builtin helpers also use it as param
+// to get the raw value.
+type cellValue struct {
+	value    interface{}
+	link     *cellLink
+	comment  *cellComment
+	dropdown []string
+	style    string
+}
+
+// merge copies the attributes of o into c, o has higher priority.
+func (c *cellValue) merge(o *cellValue) {
+	if o.link != nil {
+		c.link = o.link
+	}
+	if o.comment != nil {
+		c.comment = o.comment
+	}
+	if o.dropdown != nil {
+		c.dropdown = o.dropdown
+	}
+	if o.style != "" {
+		c.style = o.style
+	}
+}
+
+const (
+	linkExternal = "External"
+	linkLocation = "Location"
+)
+
+type cellLink struct {
+	target   string
+	linkType string
+}
+
+// newCellLink guesses link type by target:
+// `#Sheet1!A1` or `Sheet1!A1` is a location in workbook,
+// `https://...` and `mailto:...` are external.
+func newCellLink(target string) *cellLink {
+	switch {
+	case strings.HasPrefix(target, "#"):
+		return &cellLink{target: target[1:], linkType: linkLocation}
+	case strings.Contains(target, "://"), strings.HasPrefix(target, "mailto:"):
+		return &cellLink{target: target, linkType: linkExternal}
+	case strings.Contains(target, "!"):
+		return &cellLink{target: target, linkType: linkLocation}
+	default:
+		return &cellLink{target: target, linkType: linkExternal}
+	}
+}
+
+// link renders a hyperlink cell, e.g. `{{link url "text"}}`.
+func link(target, text string) *cellValue {
+	return &cellValue{value: text, link: newCellLink(target)}
+}
+
+type cellComment struct {
+	Author string `json:"author"`
+	Text   string `json:"text"`
+}
+
+// comment attaches a comment to the cell and renders nothing,
+// e.g. `{{amount}}{{comment "author" note}}`.
+func comment(author, text string) *cellValue {
+	return &cellValue{value: "", comment: &cellComment{Author: author, Text: text}}
+}
+
+// dropdown attaches a dropdown list to the cell and renders nothing,
+// options is a collection from data like `{{dropdown statuses}}`,
+// or a comma separated string like `{{dropdown "open,closed"}}`.
+func dropdown(options string) (*cellValue, error) {
+	var items []interface{}
+	if err := json.Unmarshal([]byte(options), &items); err != nil {
+		items = nil
+		for _, o := range strings.Split(options, ",") {
+			items = append(items, o)
+		}
+	}
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		if s := toString(item); s != "" {
+			list = append(list, s)
+		}
+	}
+	return &cellValue{value: "", dropdown: list}, nil
+}
+
+// collectionValues returns the values of path in each item of collection,
+// path is a field path like `price` or `product.price`, blank path means the
+// item itself. Only slice and array are supported, the item should be a map
+// or a struct when path isn't blank.
+func collectionValues(collection interface{}, path string) ([]interface{}, error) {
+	if collection == nil {
+		return nil, nil
+	}
+	rv := reflect.ValueOf(collection)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("Aggregate value %v is not a slice or array.", rv.Kind())
+	}
+	values := make([]interface{}, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		item := rv.Index(i).Interface()
+		if path != "" {
+			skm, err := toStringKeyMap(item)
+			if err != nil {
+				return nil, fmt.Errorf("Aggregate item %v has no field `%s`.", item, path)
+			}
+			if item, err = lookupPath(skm, path); err != nil {
+				return nil, err
+			}
+		}
+		values = append(values, item)
+	}
+	return values, nil
+}
+
+// numbers returns the number values of path in collection.
+func numbers(collection interface{}, path string) ([]float64, error) {
+	vs, err := collectionValues(collection, path)
+	if err != nil {
+		return nil, err
+	}
+	var result []float64
+	for _, v := range vs {
+		if f, ok := toFloat(v); ok {
+			result = append(result, f)
+		}
+	}
+	return result, nil
+}
+
+// aggSum returns the sum of path in collection, e.g. `{{sum items "price"}}`.
+func aggSum(collection interface{}, path string) (float64, error) {
+	fs, err := numbers(collection, path)
+	var result float64
+	for _, f := range fs {
+		result += f
+	}
+	return result, err
+}
+
+// aggCount returns the count of items in collection, e.g. `{{count items}}`.
+func aggCount(collection interface{}) (int, error) {
+	vs, err := collectionValues(collection, "")
+	return len(vs), err
+}
+
+// aggAvg returns the average of path in collection, zero when no numbers.
+func aggAvg(collection interface{}, path string) (float64, error) {
+	fs, err := numbers(collection, path)
+	if err != nil || len(fs) == 0 {
+		return 0, err
+	}
+	s, _ := aggSum(collection, path)
+	return s / float64(len(fs)), nil
+}
+
+// aggMin returns the min of path in collection, zero when no numbers.
+func aggMin(collection interface{}, path string) (float64, error) {
+	fs, err := numbers(collection, path)
+	if err != nil || len(fs) == 0 {
+		return 0, err
+	}
+	result := fs[0]
+	for _, f := range fs[1:] {
+		if f < result {
+			result = f
+		}
+	}
+	return result, nil
+}
+
+// aggMax returns the max of path in collection, zero when no numbers.
+func aggMax(collection interface{}, path string) (float64, error) {
+	fs, err := numbers(collection, path)
+	if err != nil || len(fs) == 0 {
+		return 0, err
+	}
+	result := fs[0]
+	for _, f := range fs[1:] {
+		if f > result {
+			result = f
+		}
+	}
+	return result, nil
+}
+
+// unwrapCellValue returns the raw value of v, and merges the attributes of v
+// into acc when v is a *cellValue.
+func unwrapCellValue(v interface{}, acc *cellValue) (interface{}, *cellValue) {
+	cv, ok := v.(*cellValue)
+	if !ok || cv == nil {
+		return v, acc
+	}
+	if acc == nil {
+		acc = &cellValue{}
+	}
+	acc.merge(cv)
+	return cv.value, acc
+}
+
+// wrapCellValue attaches the attributes of attr to v,
+// the attributes already in v have higher priority.
+func wrapCellValue(v interface{}, attr *cellValue) interface{} {
+	result := &cellValue{value: v}
+	result.merge(attr)
+	if cv, ok := v.(*cellValue); ok && cv != nil {
+		result.value = cv.value
+		result.merge(cv)
+	}
+	return result
+}
@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/360EntSecGroup-Skylar/excelize/v2"
 	"github.com/SmallTianTian/go-tools/slice"
@@ -16,11 +19,31 @@ const (
 	ctxCacheKey    = "_xlsxt_ctx"
 	renderCacheKey = "_xlsxt_render_cache"
 	sheetDataKey   = "_xlsxt_sheet_data"
+
+	// rootKey is the key of sheet data, parentKey is the key of
+	// the data of enclosing scope in range.
+	rootKey   = "$root"
+	parentKey = "$parent"
+
+	// keyPathPattern is the key path of block like range, group and with.
+	keyPathPattern = `([^\s{}]*)`
 )
 
 var (
-	rangeRgx    = regexp.MustCompile(`{{range (\w*)}}`)
+	// {{range items}} or {{range $item := items}}, items is a key path.
+	rangeRgx    = regexp.MustCompile(`{{range (?:(\$\w+) := )?` + keyPathPattern + `(?: ([^{}]*))?}}`)
 	rowRangeRgx = regexp.MustCompile(`{{rowRange (\w*)}}`)
+	groupRgx    = regexp.MustCompile(`{{group ` + keyPathPattern + ` by (\w+)( outline)?}}`)
+	// {{with customer.address}}, the key path is the scope of the block.
+	withRgx = regexp.MustCompile(`{{with ` + keyPathPattern + `}}`)
+	// {{$total := sum items "amount"}} defines variable for subsequent cells.
+	assignRgx = regexp.MustCompile(`(?s)^{{(\$\w+) := (.+)}}$`)
+	// {{include "Header"}} inlines the rows of sheet in template or partials.
+	includeRgx = regexp.MustCompile(`{{include "([^"]+)"}}`)
+	// the blocks end with {{end}}
+	blockRgxs = []*regexp.Regexp{rangeRgx, groupRgx, withRgx}
+	// {{pageBreak 20}} or {{pageBreak group}}, with optional header rows count.
+	pageBreakRgx = regexp.MustCompile(`{{pageBreak (\w+)(?: (\d+))?}}`)
 )
 
 // 错误码从 20000 开始
@@ -28,6 +51,9 @@ var (
 	NotStringKeyMapValue = errors.New("code: 20000, Not a string key map value.")
 	NotMatchRangeEnd     = errors.New("code: 20001, Range not match end.")
 	RenderCancel         = errors.New("code: 20002, range is cancel.")
+	NotRangeValue        = errors.New("code: 20003, Range value is not a collection.")
+	NotFoundPartial      = errors.New("code: 20004, Partial sheet not found.")
+	IncludeCycle         = errors.New("code: 20005, Partial sheet includes itself.")
 )
 
 type Xlsxt struct {
@@ -35,10 +61,19 @@ type Xlsxt struct {
 	buf  bytes.Buffer
 
 	// private
-	ctx          context.Context
-	cacheRender  map[string]*Parse
-	sheetData    map[string]interface{}
-	curSheetData map[string]interface{}
+	ctx           context.Context
+	cacheRender   map[string]*Parse
+	sheetData     map[string]interface{}
+	curSheetData  map[string]interface{}
+	curSheet      *sheetRender
+	lists         *listSheet
+	condFormats   map[string][]condFormat
+	out           *excelize.File
+	styles        map[string]int
+	sheets        map[string]*sheetRender
+	partials      []*excelize.File
+	partialSheets map[string]*partialSheet
+	tplStyles     map[tplStyle]int
 }
 
 func NewFromBinary(content []byte) (res *Xlsxt, err error) {
@@ -46,7 +81,29 @@ func NewFromBinary(content []byte) (res *Xlsxt, err error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Xlsxt{file: f, cacheRender: make(map[string]*Parse)}, nil
+	return &Xlsxt{
+		file:          f,
+		cacheRender:   make(map[string]*Parse),
+		condFormats:   make(map[string][]condFormat),
+		partialSheets: make(map[string]*partialSheet),
+	}, nil
+}
+
+// NewConditionalStyle creates style for conditional format,
+// the result could be used as `format` in SetConditionalFormat.
+func (m *Xlsxt) NewConditionalStyle(style string) (int, error) {
+	return m.file.NewConditionalStyle(style)
+}
+
+// SetConditionalFormat adds conditional format to the area of sheet in template,
+// the area will be expanded to the rendered rows. formatSet is a template
+// rendered with sheet data, see excelize.File.SetConditionalFormat for details.
+func (m *Xlsxt) SetConditionalFormat(sheet, area, formatSet string) error {
+	if m.file.GetSheetIndex(sheet) == -1 {
+		return fmt.Errorf("sheet %s is not exist", sheet)
+	}
+	m.condFormats[sheet] = append(m.condFormats[sheet], condFormat{area: area, formatSet: formatSet})
+	return nil
 }
 
 // Render renders report and stores it in a struct
@@ -70,8 +127,33 @@ func (m *Xlsxt) Result() bytes.Buffer {
 
 func (m *Xlsxt) defaultRender(data map[string]interface{}) (buf bytes.Buffer, err error) {
 	f := excelize.NewFile()
+	m.out, m.styles, m.tplStyles = f, make(map[string]int), make(map[tplStyle]int)
+	m.sheets = make(map[string]*sheetRender)
+	m.lists = &listSheet{refs: make(map[string]string)}
+	// keep the dxf ids of conditional formats in template
+	if m.file.Styles.Dxfs != nil {
+		dxfs := *m.file.Styles.Dxfs
+		dxfs.Dxfs = dxfs.Dxfs[:len(dxfs.Dxfs):len(dxfs.Dxfs)]
+		f.Styles.Dxfs = &dxfs
+	}
+	hidden, err := m.hiddenPartials()
+	if err != nil {
+		return
+	}
 	sns := m.file.GetSheetList()
+	var i int
 	for _, sn := range sns {
+		// result has the same sheets as template, except the hidden partials
+		if hidden[sn] {
+			continue
+		}
+		if i++; i == 1 {
+			if n := f.GetSheetName(0); n != sn {
+				f.SetSheetName(n, sn)
+			}
+		} else {
+			f.NewSheet(sn)
+		}
 		var (
 			baseColWidth     excelize.BaseColWidth
 			defaultColWidth  excelize.DefaultColWidth
@@ -84,11 +166,15 @@ func (m *Xlsxt) defaultRender(data map[string]interface{}) (buf bytes.Buffer, er
 		if err = m.file.GetSheetFormatPr(sn, &baseColWidth, &defaultColWidth, &defaultRowHeight, &customHeight, &zeroHeight, &thickTop, &thickBottom); err != nil {
 			return
 		}
-		var ssw *excelize.StreamWriter
-		if ssw, err = f.NewStreamWriter(sn); err != nil {
+		// sheet format and views are written when the stream writer is created
+		if err = f.SetSheetFormatPr(sn, &baseColWidth, &defaultColWidth, &defaultRowHeight, &customHeight, &zeroHeight, &thickTop, &thickBottom); err != nil {
 			return
 		}
-		if err = f.SetSheetFormatPr(sn, &baseColWidth, &defaultColWidth, &defaultRowHeight, &customHeight, &zeroHeight, &thickTop, &thickBottom); err != nil {
+		if err = m.copySheetViews(f, sn); err != nil {
+			return
+		}
+		var ssw *excelize.StreamWriter
+		if ssw, err = f.NewStreamWriter(sn); err != nil {
 			return
 		}
 		// TODO get all rows height?
@@ -100,21 +186,50 @@ func (m *Xlsxt) defaultRender(data map[string]interface{}) (buf bytes.Buffer, er
 		if m.sheetData, err = getSheetData(data, sn, sns); err != nil {
 			return
 		}
+		m.curSheetData = mergeMap(m.sheetData, map[string]interface{}{rootKey: m.sheetData})
 		// remove current sheet data in other sheet
 		delete(data, sn)
-		if _, err = m.renderRows(ssw, rowsData, 0); err != nil {
+		if m.curSheet, err = m.newSheetRender(sn); err != nil {
+			return
+		}
+		m.sheets[sn] = m.curSheet
+		if m.curSheet.outRows, err = m.renderRows(ssw, rowsData, 0, 0); err != nil {
 			return
 		}
+		m.curSheet.tplRows = len(rowsData)
 		if err = ssw.Flush(); err != nil {
 			return
 		}
-
+		if err = m.curSheet.copyConditionalFormats(m.file, f); err != nil {
+			return
+		}
+		if err = m.curSheet.copyTables(m.file, f); err != nil {
+			return
+		}
+		if err = m.curSheet.copyAutoFilter(m.file, f); err != nil {
+			return
+		}
+		if err = m.copyPageSetup(f, sn); err != nil {
+			return
+		}
+		if err = m.curSheet.writeTo(f); err != nil {
+			return
+		}
 	}
+	if err = m.lists.writeTo(f); err != nil {
+		return
+	}
+	if err = m.copyDefinedNames(f, hidden); err != nil {
+		return
+	}
+	f.SetActiveSheet(f.GetSheetIndex(m.file.GetSheetName(m.file.GetActiveSheetIndex())))
 	b, e := f.WriteToBuffer()
 	return *b, e
 }
 
-func (m *Xlsxt) renderRows(write *excelize.StreamWriter, rowsData [][]string, rowOffset int) (renderLine int, err error) {
+// renderRows renders rowsData, tplOffset is the template row offset of rowsData,
+// rowOffset is the row offset of result.
+func (m *Xlsxt) renderRows(write *excelize.StreamWriter, rowsData [][]string, tplOffset, rowOffset int) (renderLine int, err error) {
 	var axis string
 	for w := 0; w < len(rowsData); {
 		if m.ctx.Err() != nil {
@@ -128,27 +243,65 @@ func (m *Xlsxt) renderRows(write *excelize.StreamWriter, rowsData [][]string, ro
 		// empty line
 		if len(cells) == 0 {
 			write.SetRow(axis, nil)
+			m.curSheet.mapRow(w+1+tplOffset, renderLine+1+rowOffset)
 			renderLine++
 			w++
 			continue
 		}
 
+		// group begin
+		if ms := groupRgx.FindStringSubmatch(cells[0]); len(ms) == 4 {
+			end := getEndRowIndex(rowsData[w+1:])
+			if end == -1 {
+				return 0, NotMatchRangeEnd
+			}
+			m.curSheet.addRange(w+1+tplOffset, w+end+1+tplOffset)
+			var rl int
+			if rl, err = m.renderGroup(write, ms[1], ms[2], ms[3] != "", rowsData[w+1:w+end], w+1+tplOffset, renderLine+rowOffset); err != nil {
+				return
+			}
+			renderLine += rl
+			w += end + 1
+			continue
+		}
+
+		// with begin
+		if ms := withRgx.FindStringSubmatch(cells[0]); len(ms) == 2 {
+			end := getEndRowIndex(rowsData[w+1:])
+			if end == -1 {
+				return 0, NotMatchRangeEnd
+			}
+			m.curSheet.addRange(w+1+tplOffset, w+end+1+tplOffset)
+			var rl int
+			if rl, err = m.renderWith(write, ms[1], rowsData[w+1:w+end], w+1+tplOffset, renderLine+rowOffset); err != nil {
+				return
+			}
+			renderLine += rl
+			w += end + 1
+			continue
+		}
+
 		// range begin
-		if ms := rangeRgx.FindStringSubmatch(cells[0]); len(ms) == 2 {
-			rangeKey := ms[1]
+		if ms := rangeRgx.FindStringSubmatch(cells[0]); len(ms) == 4 {
+			var rc *rangeClause
+			if rc, err = newRangeClause(ms[1], ms[2], ms[3]); err != nil {
+				return
+			}
 			end := getEndRowIndex(rowsData[w+1:])
 			// can't find end
 			if end == -1 {
 				return 0, NotMatchRangeEnd
 			}
+			m.curSheet.addRange(w+1+tplOffset, w+end+1+tplOffset)
 			// skip range line
 			// no valid render line
 			if end == 1 {
 				w += 2
 				continue
 			}
+			pb := newPageBreak(cells, rowsData[:w], tplOffset)
 			var rl int
-			if rl, err = m.renderRangeRow(write, rangeKey, rowsData[w+1:w+end], w+rowOffset); err != nil {
+			if rl, err = m.renderRangeRow(write, rc, rowsData[w+1:w+end], w+1+tplOffset, renderLine+rowOffset, pb); err != nil {
 				return
 			}
 			renderLine += rl
@@ -157,61 +310,333 @@ func (m *Xlsxt) renderRows(write *excelize.StreamWriter, rowsData [][]string, ro
 			continue
 		}
 
+		// include partial
+		if ms := includeRgx.FindStringSubmatch(cells[0]); len(ms) == 2 {
+			var rl int
+			if rl, err = m.renderInclude(write, ms[1], renderLine+rowOffset); err != nil {
+				return
+			}
+			renderLine += rl
+			w++
+			continue
+		}
+
+		// variables row isn't rendered
+		if isAssignRow(cells) {
+			for _, c := range cells {
+				if c == "" {
+					continue
+				}
+				if err = m.assign(c); err != nil {
+					return
+				}
+			}
+			w++
+			continue
+		}
+
 		// no row range
 		rowResultData := make([]interface{}, 0, len(rowsData[w]))
-		for _, item := range rowsData[w] {
+		for i, item := range rowsData[w] {
 			var cellResult *excelize.Cell
-			if cellResult, err = m.renderCells(item); err != nil {
+			if cellResult, err = m.renderCell(item, i+1, w+1+tplOffset, renderLine+1+rowOffset); err != nil {
 				return
 			}
 			rowResultData = append(rowResultData, cellResult)
 		}
 		write.SetRow(axis, rowResultData)
+		m.curSheet.mapRow(w+1+tplOffset, renderLine+1+rowOffset)
 		renderLine++
 		w++
 	}
 	return
 }
 
-func (m *Xlsxt) renderRangeRow(write *excelize.StreamWriter, rangeKey string, rowsData [][]string, offset int) (renderLine int, err error) {
-	rangeD, has := m.sheetData[rangeKey]
+// renderRangeRow renders rowsData once per item of range key, the item is merged
+// over current data, and named by range var if it isn't blank.
+func (m *Xlsxt) renderRangeRow(write *excelize.StreamWriter, rc *rangeClause, rowsData [][]string, tplOffset, offset int, pb *pageBreak) (renderLine int, err error) {
+	rangeD, err := lookupPath(m.curSheetData, rc.key)
+	if err != nil {
+		return 0, err
+	}
 	// no valid render data
-	if !has {
+	if rangeD == nil {
 		return len(rowsData), nil
 	}
-	m.curSheetData = excludeKeyMap(m.sheetData, rangeKey)
+	parentData := m.curSheetData
+	defer func() { m.curSheetData = parentData }()
+	scope := excludeKeyMap(parentData, rc.key)
 
-	dc := getChanKeyMap(rangeD)
-	for i := 0; ; i++ {
-		if v, ok := <-dc; !ok {
-			break
-		} else {
-			m.curSheetData = mergeMap(m.curSheetData, v)
-
-			l, err := m.renderRows(write, rowsData, offset)
+	iter, err := newRangeIter(rangeD)
+	if err != nil {
+		return 0, err
+	}
+	count, known := collectionLen(rangeD)
+	// the count of channel is known after all items are received,
+	// and the clauses need all items too
+	if !known && usedInRows(rowsData, countKey) || rc.hasClause() {
+		var items []map[string]interface{}
+		for {
+			item, ok, err := iter()
+			if err != nil {
+				return 0, err
+			}
+			if !ok {
+				break
+			}
+			items = append(items, item)
+		}
+		if items, err = rc.apply(m, items, func(item map[string]interface{}) map[string]interface{} {
+			return m.itemScope(scope, parentData, rc.name, item, nil)
+		}); err != nil {
+			return 0, err
+		}
+		count, known = len(items), true
+		iter, _ = newRangeIter(items)
+	}
+	// get the next item ahead to know whether it's the last
+	next, ok, err := iter()
+	for i := 0; ok; i++ {
+		if err != nil {
+			return 0, err
+		}
+		v := next
+		if next, ok, err = iter(); err != nil {
+			return 0, err
+		}
+		if pb.next(i, v) {
+			m.curSheet.breaks = append(m.curSheet.breaks, offset+1)
+			m.curSheetData = parentData
+			l, err := m.renderHeaderRows(write, pb.header, pb.tplOffset, offset)
 			if err != nil {
 				return 0, err
 			}
 			renderLine += l
 			offset += l
 		}
+		meta := loopMeta(i, !ok)
+		if known {
+			meta[countKey] = count
+		}
+		m.curSheetData = m.itemScope(scope, parentData, rc.name, v, meta)
+
+		l, err := m.renderRows(write, rowsData, tplOffset, offset)
+		if err != nil {
+			return 0, err
+		}
+		renderLine += l
+		offset += l
 	}
 	return
 }
 
+// renderWith renders rowsData with the value of key merged over current data,
+// the block is skipped when the value is empty.
+func (m *Xlsxt) renderWith(write *excelize.StreamWriter, key string, rowsData [][]string, tplOffset, offset int) (renderLine int, err error) {
+	v, err := lookupPath(m.curSheetData, key)
+	if err != nil {
+		return 0, err
+	}
+	if isEmpty(v) {
+		return 0, nil
+	}
+	item, err := rangeItem(v)
+	if err != nil {
+		return 0, err
+	}
+	parentData := m.curSheetData
+	defer func() { m.curSheetData = parentData }()
+	m.curSheetData = m.itemScope(parentData, parentData, "", item, nil)
+	return m.renderRows(write, rowsData, tplOffset, offset)
+}
+
+// isEmpty reports whether v is nil, false, zero, blank string or empty collection.
+func isEmpty(v interface{}) bool {
+	if n, ok := collectionLen(v); ok {
+		return n == 0
+	}
+	return !truthy(v)
+}
+
+// pageBreak inserts page break before the range item,
+// after every `every` items or when the value of `groupKey` changes,
+// and repeats the header rows after the break.
+type pageBreak struct {
+	every     int
+	groupKey  string
+	header    [][]string
+	tplOffset int
+
+	last string
+}
+
+// newPageBreak parses `{{pageBreak 20 2}}` or `{{pageBreak group 2}}` in the range line,
+// the last number is the count of rows above the range line to repeat.
+func newPageBreak(rangeLine []string, above [][]string, tplOffset int) *pageBreak {
+	for _, c := range rangeLine {
+		ms := pageBreakRgx.FindStringSubmatch(c)
+		if len(ms) != 3 {
+			continue
+		}
+		pb := &pageBreak{}
+		if n, err := strconv.Atoi(ms[1]); err == nil {
+			pb.every = n
+		} else {
+			pb.groupKey = ms[1]
+		}
+		if n, _ := strconv.Atoi(ms[2]); n > 0 {
+			if n > len(above) {
+				n = len(above)
+			}
+			pb.header = above[len(above)-n:]
+			pb.tplOffset = tplOffset + len(above) - n
+		}
+		return pb
+	}
+	return nil
+}
+
+// next reports whether a page break is needed before the i-th item.
+func (pb *pageBreak) next(i int, item map[string]interface{}) bool {
+	if pb == nil {
+		return false
+	}
+	if pb.every > 0 {
+		return i > 0 && i%pb.every == 0
+	}
+	group := toString(item[pb.groupKey])
+	brk := i > 0 && group != pb.last
+	pb.last = group
+	return brk
+}
+
+// renderHeaderRows renders the repeated header rows, the rows are not mapped
+// to template, so the template attributes of them won't be expanded.
+func (m *Xlsxt) renderHeaderRows(write *excelize.StreamWriter, rowsData [][]string, tplOffset, offset int) (renderLine int, err error) {
+	for w, cells := range rowsData {
+		var axis string
+		if axis, err = excelize.CoordinatesToCellName(1, offset+w+1); err != nil {
+			return
+		}
+		row := make([]interface{}, 0, len(cells))
+		for i, item := range cells {
+			var c *excelize.Cell
+			if c, err = m.renderCell(item, i+1, w+1+tplOffset, offset+w+1); err != nil {
+				return
+			}
+			row = append(row, c)
+		}
+		if err = write.SetRow(axis, row); err != nil {
+			return
+		}
+		renderLine++
+	}
+	return
+}
+
+// renderCell renders the cell in template (col, tplRow) to (col, row),
+// and records the cell attributes like hyperlink.
+func (m *Xlsxt) renderCell(tlp string, col, tplRow, row int) (c *excelize.Cell, err error) {
+	if c, err = m.renderCells(tlp); err != nil {
+		return
+	}
+	var tplAxis, axis string
+	if tplAxis, err = excelize.CoordinatesToCellName(col, tplRow); err != nil {
+		return
+	}
+	if axis, err = excelize.CoordinatesToCellName(col, row); err != nil {
+		return
+	}
+
+	cv, ok := c.Value.(*cellValue)
+	if ok {
+		c.Value = cv.value
+	} else {
+		cv = &cellValue{}
+	}
+	if m.curSheet.inPartial() {
+		inc := m.curSheet.includes[len(m.curSheet.includes)-1]
+		if id, in := inc.styles[tplAxis]; in {
+			if c.StyleID, err = m.copyStyle(inc.file, id); err != nil {
+				return
+			}
+		}
+		// the cell attributes of partial aren't in template
+		tplAxis = ""
+	}
+	if cv.style != "" {
+		if c.StyleID, err = m.styleID(cv.style); err != nil {
+			return
+		}
+	}
+	// link in template, target is a template too.
+	if tl, in := m.curSheet.tplLinks[tplAxis]; in && cv.link == nil {
+		var target string
+		if target, err = m.renderString(tl.target); err != nil {
+			return
+		}
+		cv.link = &cellLink{target: target, linkType: tl.linkType}
+	}
+	if cv.link != nil && cv.link.target != "" {
+		m.curSheet.links = append(m.curSheet.links, axisLink{axis: axis, link: cv.link})
+	}
+
+	// comment in template, author and text are templates too.
+	if tc, in := m.curSheet.tplComments[tplAxis]; in && cv.comment == nil {
+		cv.comment = &cellComment{}
+		if cv.comment.Author, err = m.renderString(tc.Author); err != nil {
+			return
+		}
+		if cv.comment.Text, err = m.renderString(tc.Text); err != nil {
+			return
+		}
+	}
+	if cv.comment != nil && cv.comment.Text != "" {
+		m.curSheet.comments = append(m.curSheet.comments, axisComment{axis: axis, comment: cv.comment})
+	}
+	if len(cv.dropdown) > 0 {
+		m.curSheet.addDropdown(axis, cv.dropdown)
+	}
+	return
+}
+
+// renderString renders tlp with current data to string.
+func (m *Xlsxt) renderString(tlp string) (string, error) {
+	c, err := m.renderCells(tlp)
+	if err != nil {
+		return "", err
+	}
+	return toString(c.Value), nil
+}
+
+// parse returns the cached parse of template.
+func (m *Xlsxt) parse(tlp string) (*Parse, error) {
+	if tp, in := m.cacheRender[tlp]; in {
+		return tp, nil
+	}
+	tp, err := NewParse(tlp)
+	if err != nil {
+		return nil, err
+	}
+	m.cacheRender[tlp] = tp
+	return tp, nil
+}
+
 func (m *Xlsxt) renderCells(tlp string) (a *excelize.Cell, err error) {
+	// the assignment cell is blank
+	if assignRgx.MatchString(tlp) {
+		return &excelize.Cell{}, m.assign(tlp)
+	}
 
 	defer func() {
 		if e := recover(); e != nil {
 			err = fmt.Errorf("code: 20002, UNKNOW ERR. %v", e)
 		}
 	}()
-	if _, in := m.cacheRender[tlp]; !in {
-		if m.cacheRender[tlp], err = NewParse(tlp); err != nil {
-			return
-		}
+	tp, err := m.parse(tlp)
+	if err != nil {
+		return
 	}
-	tp := m.cacheRender[tlp]
 	var v interface{}
 
 	if v, err = tp.Exec(m.ctx, m.curSheetData); err != nil {
@@ -235,13 +660,120 @@ func getEndRowIndex(rowsData [][]string) int {
 			inStack--
 			continue
 		}
-		if rangeRgx.MatchString(fV) {
+		if isBlockBegin(fV) {
 			inStack++
 		}
 	}
 	return -1
 }
 
+// assign evaluates the assignment like `{{$total := sum items "amount"}}`,
+// the variable is visible to subsequent cells of the sheet or block.
+func (m *Xlsxt) assign(tlp string) error {
+	ms := assignRgx.FindStringSubmatch(tlp)
+	tp, err := m.parse("{{" + ms[2] + "}}")
+	if err != nil {
+		return err
+	}
+	v, err := tp.value(m.ctx, m.curSheetData)
+	if err != nil {
+		return err
+	}
+	m.curSheetData = mergeMap(m.curSheetData, map[string]interface{}{ms[1]: v})
+	return nil
+}
+
+// isAssignRow reports whether the cells of row are all assignments.
+func isAssignRow(cells []string) bool {
+	var n int
+	for _, c := range cells {
+		if c == "" {
+			continue
+		}
+		if !assignRgx.MatchString(c) {
+			return false
+		}
+		n++
+	}
+	return n > 0
+}
+
+// isBlockBegin reports whether s begins a block like range, group or with.
+func isBlockBegin(s string) bool {
+	for _, rgx := range blockRgxs {
+		if rgx.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// itemScope returns the data of range item, the item is merged over scope,
+// and the root data, parent data and item could be referred by `$root`,
+// `$parent` and the name of item. meta is the loop metadata like `$index`.
+func (m *Xlsxt) itemScope(scope, parent map[string]interface{}, name string, item, meta map[string]interface{}) map[string]interface{} {
+	result := mergeMap(scope, item)
+	for k, v := range meta {
+		result[k] = v
+	}
+	result[rootKey] = m.sheetData
+	result[parentKey] = parent
+	if name != "" {
+		result[name] = item
+	}
+	return result
+}
+
+// loop metadata keys in range
+const (
+	indexKey  = "$index"
+	numberKey = "$number"
+	firstKey  = "$first"
+	lastKey   = "$last"
+	evenKey   = "$even"
+	oddKey    = "$odd"
+	countKey  = "$count"
+)
+
+// loopMeta returns the loop metadata of the i-th item, `$even` and `$odd`
+// are by the 0-based `$index`, `$number` is 1-based.
+func loopMeta(i int, last bool) map[string]interface{} {
+	return map[string]interface{}{
+		indexKey:  i,
+		numberKey: i + 1,
+		firstKey:  i == 0,
+		lastKey:   last,
+		evenKey:   i%2 == 0,
+		oddKey:    i%2 == 1,
+	}
+}
+
+// collectionLen returns the length of collection, channel is unknown.
+func collectionLen(v interface{}) (int, bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len(), true
+	default:
+		return 0, false
+	}
+}
+
+// usedInRows reports whether s is used in the templates of rows.
+func usedInRows(rowsData [][]string, s string) bool {
+	for _, row := range rowsData {
+		for _, cell := range row {
+			if strings.Contains(cell, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func getSheetData(in map[string]interface{}, sn string, allSN []string) (result map[string]interface{}, err error) {
 	if result, err = toStringKeyMap(in[sn]); err != nil {
 		return
@@ -272,66 +804,102 @@ func mergeMap(dist, fresh map[string]interface{}) map[string]interface{} {
 	return result
 }
 
-func getChanKeyMap(v interface{}) <-chan map[string]interface{} {
-	if ckm, ok := v.(chan map[string]interface{}); ok {
-		return ckm
+// rangeIter returns the next item of collection,
+// ok is false when there is no more items.
+type rangeIter func() (item map[string]interface{}, ok bool, err error)
+
+// newRangeIter returns the iterator of collection v, which could be a slice,
+// an array, a channel or a map. The items of map are sorted by key.
+func newRangeIter(v interface{}) (rangeIter, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return func() (map[string]interface{}, bool, error) { return nil, false, nil }, nil
+		}
+		rv = rv.Elem()
 	}
-	c := make(chan map[string]interface{})
-	if akm, ok := v.([]map[string]interface{}); ok {
-		go func() {
-			for _, v := range akm {
-				c <- v
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		i := 0
+		return func() (map[string]interface{}, bool, error) {
+			if i >= rv.Len() {
+				return nil, false, nil
 			}
-			close(c)
-		}()
-		return c
-	}
-
-	var (
-		rt reflect.Type
-		rv reflect.Value
-	)
-NoPtr:
-	rt = reflect.TypeOf(v)
-	rv = reflect.ValueOf(v)
-	if rt.Kind() == reflect.Ptr && rv.CanAddr() {
-		v = rv.Addr().Interface()
-		goto NoPtr
-	}
-
-	if rt.Kind() != reflect.Array && rt.Kind() != reflect.Chan && rt.Kind() != reflect.Slice {
-		close(c)
-		return c
-	}
-
-	if rt.Kind() == reflect.Chan {
-		go func() {
-			for {
-				if v, ok := rv.Recv(); ok {
-					if skm, err := toStringKeyMap(v.Interface()); err != nil {
-						break
-					} else {
-						c <- skm
-					}
-				} else {
-					break
-				}
+			i++
+			item, err := rangeItem(rv.Index(i - 1).Interface())
+			return item, true, err
+		}, nil
+	case reflect.Chan:
+		return func() (map[string]interface{}, bool, error) {
+			x, ok := rv.Recv()
+			if !ok {
+				return nil, false, nil
+			}
+			item, err := rangeItem(x.Interface())
+			return item, true, err
+		}, nil
+	case reflect.Map:
+		keys := sortedMapKeys(rv)
+		i := 0
+		return func() (map[string]interface{}, bool, error) {
+			if i >= len(keys) {
+				return nil, false, nil
 			}
-			close(c)
-		}()
-		return c
+			k := keys[i]
+			i++
+			return mapEntryItem(k.Interface(), rv.MapIndex(k).Interface()), true, nil
+		}, nil
+	default:
+		return nil, NotRangeValue
 	}
+}
 
-	go func() {
-		l := rv.Len()
-		for i := 0; i < l; i++ {
-			if skm, err := toStringKeyMap(rv.Index(i).Interface()); err != nil {
-				break
-			} else {
-				c <- skm
-			}
+// range item keys of scalar and map entry
+const (
+	dotKey   = "."
+	keyKey   = "$key"
+	valueKey = "$value"
+)
+
+// rangeItem converts item of collection to data, the fields of map and struct
+// (or pointer to them) are used directly, others could be referred by `.` or
+// `$value`.
+func rangeItem(v interface{}) (map[string]interface{}, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Map || rv.Kind() == reflect.Struct && rv.Type() != typeOfTime {
+		return toStringKeyMap(rv.Interface())
+	}
+	return map[string]interface{}{dotKey: v, valueKey: v}, nil
+}
+
+// mapEntryItem converts the entry of map to data, the key and value could be
+// referred by `$key` and `$value`, fields of value are merged when it's a map.
+func mapEntryItem(k, v interface{}) map[string]interface{} {
+	item := make(map[string]interface{})
+	if skm, err := toStringKeyMap(v); err == nil && v != nil {
+		for f, fv := range skm {
+			item[f] = fv
 		}
-		close(c)
-	}()
-	return c
+	} else {
+		item[dotKey] = v
+	}
+	item[keyKey], item[valueKey] = k, v
+	return item
+}
+
+// sortedMapKeys returns the keys of map sorted by number or string.
+func sortedMapKeys(rv reflect.Value) []reflect.Value {
+	keys := rv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		fi, iok := toFloat(keys[i].Interface())
+		fj, jok := toFloat(keys[j].Interface())
+		if iok && jok {
+			return fi < fj
+		}
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	return keys
 }
@@ -6,7 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/360EntSecGroup-Skylar/excelize/v2"
 )
@@ -52,6 +54,37 @@ func checkExcelHelper(data []byte, expect [][]string) error {
 	return nil
 }
 
+// renderExcelHelper renders template which is prepared by prepare,
+// checks values of result and returns the result file.
+func renderExcelHelper(temp [][]string, prepare func(f *excelize.File), data interface{}, expect [][]string) (*excelize.File, error) {
+	f := excelize.NewFile()
+	for j, item := range temp {
+		for k, it := range item {
+			n, _ := excelize.CoordinatesToCellName(k+1, j+1)
+			f.SetCellValue("Sheet1", n, it)
+		}
+	}
+	if prepare != nil {
+		prepare(f)
+	}
+	bf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+	xl, err := NewFromBinary(bf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if err = xl.Render(nil, data); err != nil {
+		return nil, err
+	}
+	result := xl.Result()
+	if err = checkExcelHelper(result.Bytes(), expect); err != nil {
+		return nil, err
+	}
+	return excelize.OpenReader(bytes.NewReader(result.Bytes()))
+}
+
 func map2InterChanHelper(data []interface{}) chan interface{} {
 	c := make(chan interface{}, len(data))
 	for _, item := range data {
@@ -642,6 +675,35 @@ func Test_RenderFunc(t *testing.T) {
 				{"string", "f"},
 			},
 		},
+		{
+			name: "Render with pipeline.",
+			helper: map[string]interface{}{
+				"trim":  strings.TrimSpace,
+				"upper": strings.ToUpper,
+				"default": func(d, v string) string {
+					if v == "" {
+						return d
+					}
+					return v
+				},
+			},
+			args: args{
+				temp: [][]string{
+					{"{{range rows}}"},
+					{`{{k | trim | upper | default "N/A"}}`},
+					{"{{end}}"},
+				},
+				data: map[string]interface{}{
+					"rows": []map[string]interface{}{
+						{"k": " hello "}, {"k": "  "},
+					},
+				},
+			},
+			wantRes: [][]string{
+				{"HELLO"},
+				{"N/A"},
+			},
+		},
 		{
 			name: "Render with return error func",
 			helper: map[string]interface{}{"error": func(key string) (string, error) {
@@ -756,3 +818,1464 @@ func Test_RenderFunc(t *testing.T) {
 		})
 	}
 }
+
+func Test_RenderHyperlink(t *testing.T) {
+	type link struct {
+		axis   string
+		target string
+	}
+	tests := []struct {
+		name      string
+		temp      [][]string
+		tempLinks []link
+		data      interface{}
+		wantRes   [][]string
+		wantLinks []link
+	}{
+		{
+			name: "link helper",
+			temp: [][]string{
+				{"Test"},
+				{"{{range rows}}"},
+				{`{{link url name}}`, `{{link "mailto:a@b.c" "mail"}}`, `{{link "#Sheet1!A1" "top"}}`},
+				{"{{end}}"},
+			},
+			data: map[string]interface{}{
+				"rows": []map[string]interface{}{
+					{"url": "https://a.com", "name": "a"},
+					{"url": "https://b.com", "name": "b"},
+				},
+			},
+			wantRes: [][]string{
+				{"Test"},
+				{"a", "mail", "top"},
+				{"b", "mail", "top"},
+			},
+			wantLinks: []link{
+				{"A2", "https://a.com"}, {"B2", "mailto:a@b.c"}, {"C2", "Sheet1!A1"},
+				{"A3", "https://b.com"}, {"B3", "mailto:a@b.c"}, {"C3", "Sheet1!A1"},
+			},
+		},
+		{
+			name: "link in template",
+			temp: [][]string{
+				{"{{title}}"},
+				{"{{range rows}}"},
+				{"{{name}}"},
+				{"{{end}}"},
+			},
+			tempLinks: []link{{"A1", "https://a.com/{{id}}"}, {"A3", "https://a.com/{{name}}"}},
+			data: map[string]interface{}{
+				"title": "T",
+				"id":    "0",
+				"rows": []map[string]interface{}{
+					{"name": "a"}, {"name": "b"},
+				},
+			},
+			wantRes: [][]string{
+				{"T"},
+				{"a"},
+				{"b"},
+			},
+			wantLinks: []link{{"A1", "https://a.com/0"}, {"A2", "https://a.com/a"}, {"A3", "https://a.com/b"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleanHelpers()
+			rf, err := renderExcelHelper(tt.temp, func(f *excelize.File) {
+				for _, l := range tt.tempLinks {
+					f.SetCellHyperLink("Sheet1", l.axis, l.target, "External")
+				}
+			}, tt.data, tt.wantRes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, l := range tt.wantLinks {
+				if has, target, _ := rf.GetCellHyperLink("Sheet1", l.axis); !has || target != l.target {
+					t.Errorf("Link in %s = %v(%v), want %s", l.axis, target, has, l.target)
+				}
+			}
+		})
+	}
+}
+
+func Test_RenderComment(t *testing.T) {
+	type comment struct {
+		axis   string
+		author string
+		text   string
+	}
+	tests := []struct {
+		name         string
+		temp         [][]string
+		tempComments []comment
+		data         interface{}
+		wantRes      [][]string
+		wantComments []comment
+	}{
+		{
+			name: "comment helper",
+			temp: [][]string{
+				{"Test"},
+				{"{{range rows}}"},
+				{`{{name}}{{comment "Bot" note}}`},
+				{"{{end}}"},
+			},
+			data: map[string]interface{}{
+				"rows": []map[string]interface{}{
+					{"name": "a", "note": "note a"},
+					{"name": "b"},
+				},
+			},
+			wantRes: [][]string{
+				{"Test"},
+				{"a"},
+				{"b"},
+			},
+			wantComments: []comment{{"A2", "Bot", "note a"}},
+		},
+		{
+			name: "comment in template",
+			temp: [][]string{
+				{"Test"},
+				{"{{range rows}}"},
+				{"{{name}}"},
+				{"{{end}}"},
+			},
+			tempComments: []comment{{"A3", "{{author}}", "note {{name}}"}},
+			data: map[string]interface{}{
+				"author": "Bot",
+				"rows": []map[string]interface{}{
+					{"name": "a"}, {"name": "b"},
+				},
+			},
+			wantRes: [][]string{
+				{"Test"},
+				{"a"},
+				{"b"},
+			},
+			wantComments: []comment{{"A2", "Bot", "note a"}, {"A3", "Bot", "note b"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleanHelpers()
+			rf, err := renderExcelHelper(tt.temp, func(f *excelize.File) {
+				for _, c := range tt.tempComments {
+					f.AddComment("Sheet1", c.axis, fmt.Sprintf(`{"author":%q,"text":%q}`, c.author, c.text))
+				}
+			}, tt.data, tt.wantRes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			have := rf.GetComments()["Sheet1"]
+			if len(have) != len(tt.wantComments) {
+				t.Fatalf("Comments = %v, want %v", have, tt.wantComments)
+			}
+			for i, c := range tt.wantComments {
+				if have[i].Ref != c.axis || have[i].Text != c.author+c.text {
+					t.Errorf("Comment = %v, want %v", have[i], c)
+				}
+			}
+		})
+	}
+}
+
+func Test_RenderDataValidation(t *testing.T) {
+	long := make([]string, 0, 100)
+	for i := 0; i < 100; i++ {
+		long = append(long, fmt.Sprintf("option%d", i))
+	}
+	tests := []struct {
+		name      string
+		temp      [][]string
+		tempSqref string
+		data      interface{}
+		wantRes   [][]string
+		// sqref => formula1
+		wantValidations map[string]string
+		wantList        []string
+	}{
+		{
+			name: "validation in template extends with range",
+			temp: [][]string{
+				{"Test"},
+				{"{{range rows}}"},
+				{"{{name}}", "{{status}}"},
+				{"{{end}}"},
+				{"Footer"},
+			},
+			tempSqref: "B1 B3 B6:B7",
+			data: map[string]interface{}{
+				"rows": []map[string]interface{}{
+					{"name": "a", "status": "open"},
+					{"name": "b", "status": "closed"},
+					{"name": "c", "status": "open"},
+				},
+			},
+			wantRes: [][]string{
+				{"Test"},
+				{"a", "open"},
+				{"b", "closed"},
+				{"c", "open"},
+				{"Footer"},
+			},
+			wantValidations: map[string]string{"B1 B2:B4 B6:B7": `"open,closed"`},
+		},
+		{
+			name: "dropdown helper",
+			temp: [][]string{
+				{"Test"},
+				{"{{range rows}}"},
+				{"{{name}}", `{{status}}{{dropdown statuses}}`, `{{dropdown "x,y"}}`},
+				{"{{end}}"},
+			},
+			data: map[string]interface{}{
+				"statuses": []string{"open", "closed"},
+				"rows": []map[string]interface{}{
+					{"name": "a", "status": "open"},
+					{"name": "b", "status": "closed"},
+				},
+			},
+			wantRes: [][]string{
+				{"Test"},
+				{"a", "open", ""},
+				{"b", "closed", ""},
+			},
+			wantValidations: map[string]string{"B2 B3": `"open,closed"`, "C2 C3": `"x,y"`},
+		},
+		{
+			name: "long dropdown in hidden sheet",
+			temp: [][]string{
+				{"{{dropdown options}}"},
+			},
+			data: map[string]interface{}{
+				"options": long,
+			},
+			wantRes:         [][]string{{""}},
+			wantValidations: map[string]string{"A1": listSheetName + "!$A$1:$A$100"},
+			wantList:        long,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleanHelpers()
+			rf, err := renderExcelHelper(tt.temp, func(f *excelize.File) {
+				if tt.tempSqref == "" {
+					return
+				}
+				dv := excelize.NewDataValidation(true)
+				dv.Sqref = tt.tempSqref
+				dv.SetDropList([]string{"open", "closed"})
+				f.AddDataValidation("Sheet1", dv)
+			}, tt.data, tt.wantRes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rf.GetSheetFormatPr("Sheet1")
+			have := make(map[string]string)
+			if dvs := rf.Sheet[worksheetPath(rf, "Sheet1")].DataValidations; dvs != nil {
+				for _, dv := range dvs.DataValidation {
+					have[dv.Sqref] = strings.TrimSuffix(strings.TrimPrefix(dv.Formula1, "<formula1>"), "</formula1>")
+				}
+			}
+			if !reflect.DeepEqual(have, tt.wantValidations) {
+				t.Errorf("Validations = %v, want %v", have, tt.wantValidations)
+			}
+			if len(tt.wantList) > 0 {
+				if rf.GetSheetVisible(listSheetName) {
+					t.Errorf("Sheet %s should be hidden", listSheetName)
+				}
+				cols, _ := rf.GetCols(listSheetName)
+				if len(cols) != 1 || !reflect.DeepEqual(cols[0], tt.wantList) {
+					t.Errorf("List = %v, want %v", cols, tt.wantList)
+				}
+			}
+		})
+	}
+	t.Run("hidden list sheet is the last", func(t *testing.T) {
+		rf, err := renderExcelHelper([][]string{{"{{dropdown options}}"}}, func(f *excelize.File) {
+			f.NewSheet("Sheet2")
+			f.SetCellValue("Sheet2", "A1", "{{dropdown options}}")
+		}, map[string]interface{}{"options": long}, [][]string{{""}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"Sheet1", "Sheet2", listSheetName}
+		if got := rf.GetSheetList(); !reflect.DeepEqual(got, want) {
+			t.Errorf("Sheets = %v, want %v", got, want)
+		}
+	})
+}
+
+func Test_RenderConditionalFormat(t *testing.T) {
+	temp := [][]string{
+		{"Test"},
+		{"{{range rows}}"},
+		{"{{name}}", "{{score}}"},
+		{"{{end}}"},
+	}
+	f := excelize.NewFile()
+	for j, item := range temp {
+		for k, it := range item {
+			n, _ := excelize.CoordinatesToCellName(k+1, j+1)
+			f.SetCellValue("Sheet1", n, it)
+		}
+	}
+	style, _ := f.NewConditionalStyle(`{"font":{"color":"#9A0511"}}`)
+	f.SetConditionalFormat("Sheet1", "A3", fmt.Sprintf(`[{"type":"formula","criteria":"$B3>1","format":%d}]`, style))
+	bf, _ := f.WriteToBuffer()
+
+	xl, err := NewFromBinary(bf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = xl.SetConditionalFormat("Sheet1", "B3", fmt.Sprintf(`[{"type":"cell","criteria":">","format":%d,"value":"{{max}}"}]`, style)); err != nil {
+		t.Fatal(err)
+	}
+	if err = xl.SetConditionalFormat("Sheet2", "B3", `[]`); err == nil {
+		t.Error("Should be error when sheet not exist.")
+	}
+	err = xl.Render(nil, map[string]interface{}{
+		"max": 10,
+		"rows": []map[string]interface{}{
+			{"name": "a", "score": 1}, {"name": "b", "score": 2}, {"name": "c", "score": 3},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := xl.Result()
+	rf, _ := excelize.OpenReader(bytes.NewReader(result.Bytes()))
+	rf.GetSheetFormatPr("Sheet1")
+	cfs := rf.Sheet[worksheetPath(rf, "Sheet1")].ConditionalFormatting
+	if len(cfs) != 2 {
+		t.Fatalf("Conditional formats = %d, want 2", len(cfs))
+	}
+	if cfs[0].SQRef != "A2:A4" || cfs[0].CfRule[0].Formula[0] != "$B2>1" || *cfs[0].CfRule[0].DxfID != style {
+		t.Errorf("Conditional format in template = %s %v, want A2:A4 [$B2>1]", cfs[0].SQRef, cfs[0].CfRule[0].Formula)
+	}
+	if rf.Styles.Dxfs == nil || len(rf.Styles.Dxfs.Dxfs) != 1 {
+		t.Errorf("Dxfs of template should be kept.")
+	}
+	if cfs[1].SQRef != "B2:B4" || cfs[1].CfRule[0].Formula[0] != "10" {
+		t.Errorf("Conditional format added = %s %v, want B2:B4 [10]", cfs[1].SQRef, cfs[1].CfRule[0].Formula)
+	}
+}
+
+func Test_RenderStyle(t *testing.T) {
+	cleanHelpers()
+	cleanStyles()
+	RegisterStyle("danger", `{"font":{"color":"#FF0000"}}`)
+	RegisterHelper("level", func(v float64) string {
+		if v < 0 {
+			return "danger"
+		}
+		return ""
+	})
+
+	rf, err := renderExcelHelper([][]string{
+		{"Test"},
+		{"{{range rows}}"},
+		{"{{name}}", `{{style {{level amount}} amount}}`, `{{style "danger" {{link url name}}}}`},
+		{"{{end}}"},
+	}, nil, map[string]interface{}{
+		"rows": []map[string]interface{}{
+			{"name": "a", "amount": -5, "url": "https://a.com"},
+			{"name": "b", "amount": 5, "url": "https://b.com"},
+		},
+	}, [][]string{
+		{"Test"},
+		{"a", "-5", "a"},
+		{"b", "5", "b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for axis, styled := range map[string]bool{"A2": false, "B2": true, "B3": false, "C2": true, "C3": true} {
+		if s, _ := rf.GetCellStyle("Sheet1", axis); (s != 0) != styled {
+			t.Errorf("Style of %s = %d, want styled %v", axis, s, styled)
+		}
+	}
+	if has, _, _ := rf.GetCellHyperLink("Sheet1", "C2"); !has {
+		t.Error("Link of styled cell should be kept.")
+	}
+	rf.GetSheetFormatPr("Sheet1")
+	if c := rf.Sheet[worksheetPath(rf, "Sheet1")].SheetData.Row[1].C[1]; c.T != "" {
+		t.Errorf("Type of B2 = %s, want number", c.T)
+	}
+
+	_, err = renderExcelHelper([][]string{{`{{style "not_exist" "a"}}`}}, nil, nil, nil)
+	if err == nil {
+		t.Error("Should be error when style not exist.")
+	}
+}
+
+func Test_RenderDefinedName(t *testing.T) {
+	rf, err := renderExcelHelper([][]string{
+		{"Name", "Score"},
+		{"{{range rows}}"},
+		{"{{name}}", "{{score}}"},
+		{"{{end}}"},
+		{"Footer"},
+	}, func(f *excelize.File) {
+		f.NewSheet("Other Sheet")
+		f.SetSheetRow("Other Sheet", "A1", &[]string{"{{range rows}}"})
+		f.SetSheetRow("Other Sheet", "A2", &[]string{"{{name}}"})
+		f.SetSheetRow("Other Sheet", "A3", &[]string{"{{end}}"})
+		f.SetDefinedName(&excelize.DefinedName{Name: "Scores", RefersTo: "Sheet1!$B$3"})
+		f.SetDefinedName(&excelize.DefinedName{Name: "Names", RefersTo: "'Other Sheet'!$A$2"})
+		f.SetDefinedName(&excelize.DefinedName{Name: "Const", RefersTo: "3"})
+		f.SetDefinedName(&excelize.DefinedName{Name: "_xlnm.Print_Area", RefersTo: "Sheet1!$A$1:$B$5", Scope: "Sheet1"})
+		f.SetDefinedName(&excelize.DefinedName{Name: "_xlnm.Print_Titles", RefersTo: "Sheet1!$1:$1", Scope: "Sheet1"})
+	}, map[string]interface{}{
+		"rows": []map[string]interface{}{
+			{"name": "a", "score": 1}, {"name": "b", "score": 2}, {"name": "c", "score": 3}, {"name": "d", "score": 4},
+		},
+	}, [][]string{
+		{"Name", "Score"},
+		{"a", "1"},
+		{"b", "2"},
+		{"c", "3"},
+		{"d", "4"},
+		{"Footer"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"Scores":             "Sheet1!$B$2:$B$5",
+		"Names":              "'Other Sheet'!$A$1:$A$4",
+		"Const":              "3",
+		"_xlnm.Print_Area":   "Sheet1!$A$1:$B$6",
+		"_xlnm.Print_Titles": "Sheet1!$1:$1",
+	}
+	have := make(map[string]string)
+	for _, dn := range rf.GetDefinedName() {
+		have[dn.Name] = dn.RefersTo
+		if strings.HasPrefix(dn.Name, "_xlnm") && dn.Scope != "Sheet1" {
+			t.Errorf("Scope of %s = %s, want Sheet1", dn.Name, dn.Scope)
+		}
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("Defined names = %v, want %v", have, want)
+	}
+	if rows, _ := rf.GetRows("Other Sheet"); len(rows) != 4 {
+		t.Errorf("Rows of other sheet = %v, want 4 lines", rows)
+	}
+}
+
+func Test_RenderPageSetup(t *testing.T) {
+	rf, err := renderExcelHelper([][]string{
+		{"{{title}}"},
+	}, func(f *excelize.File) {
+		f.SetPageLayout("Sheet1", excelize.PageLayoutOrientation(excelize.OrientationLandscape), excelize.PageLayoutPaperSize(9), excelize.FitToWidth(1))
+		f.SetPageMargins("Sheet1", excelize.PageMarginTop(1.5))
+		f.SetSheetPrOptions("Sheet1", excelize.FitToPage(true))
+		f.SetHeaderFooter("Sheet1", &excelize.FormatHeaderFooter{
+			OddHeader: "&C&B{{title}}",
+			OddFooter: "&L{{date}}&RPage &P of &N",
+		})
+	}, map[string]interface{}{
+		"title": "Report",
+		"date":  "2020-01-01",
+	}, [][]string{
+		{"Report"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		orientation excelize.PageLayoutOrientation
+		paperSize   excelize.PageLayoutPaperSize
+		fitToWidth  excelize.FitToWidth
+		marginTop   excelize.PageMarginTop
+		fitToPage   excelize.FitToPage
+	)
+	rf.GetPageLayout("Sheet1", &orientation, &paperSize, &fitToWidth)
+	rf.GetPageMargins("Sheet1", &marginTop)
+	rf.GetSheetPrOptions("Sheet1", &fitToPage)
+	if orientation != excelize.PageLayoutOrientation(excelize.OrientationLandscape) || paperSize != 9 || fitToWidth != 1 {
+		t.Errorf("Page layout = (%v, %v, %v), want (landscape, 9, 1)", orientation, paperSize, fitToWidth)
+	}
+	if marginTop != 1.5 || !fitToPage {
+		t.Errorf("Margin top = %v, fit to page = %v, want (1.5, true)", marginTop, fitToPage)
+	}
+	hf := rf.Sheet[worksheetPath(rf, "Sheet1")].HeaderFooter
+	if hf == nil || hf.OddHeader != "&C&BReport" || hf.OddFooter != "&L2020-01-01&RPage &P of &N" {
+		t.Errorf("Header footer = %v, want rendered", hf)
+	}
+}
+
+func Test_RenderPageBreak(t *testing.T) {
+	items := []map[string]interface{}{
+		{"name": "a", "group": "g1"},
+		{"name": "b", "group": "g1"},
+		{"name": "c", "group": "g2"},
+	}
+	tests := []struct {
+		name   string
+		temp   [][]string
+		expect [][]string
+		breaks []int
+	}{
+		{
+			name: "every n items",
+			temp: [][]string{
+				{"{{range items}}{{pageBreak 2}}"},
+				{"{{name}}"},
+				{"{{end}}"},
+			},
+			expect: [][]string{{"a"}, {"b"}, {"c"}},
+			breaks: []int{2},
+		},
+		{
+			name: "group change with header",
+			temp: [][]string{
+				{"{{title}}"},
+				{"Name", "Group"},
+				{"{{range items}}", "{{pageBreak group 1}}"},
+				{"{{name}}", "{{group}}"},
+				{"{{end}}"},
+				{"Total"},
+			},
+			expect: [][]string{
+				{"Invoice"},
+				{"Name", "Group"},
+				{"a", "g1"},
+				{"b", "g1"},
+				{"Name", "Group"},
+				{"c", "g2"},
+				{"Total"},
+			},
+			breaks: []int{4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rf, err := renderExcelHelper(tt.temp, nil, map[string]interface{}{
+				"title": "Invoice",
+				"items": items,
+			}, tt.expect)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = loadWorksheet(rf, "Sheet1"); err != nil {
+				t.Fatal(err)
+			}
+			var breaks []int
+			if rb := rf.Sheet[worksheetPath(rf, "Sheet1")].RowBreaks; rb != nil {
+				for _, b := range rb.Brk {
+					breaks = append(breaks, b.ID)
+				}
+			}
+			if !reflect.DeepEqual(breaks, tt.breaks) {
+				t.Errorf("Row breaks = %v, want %v", breaks, tt.breaks)
+			}
+		})
+	}
+}
+
+func Test_RenderSheetView(t *testing.T) {
+	rf, err := renderExcelHelper([][]string{
+		{"Name", "Age"},
+		{"{{range items}}"},
+		{"{{name}}", "{{age}}"},
+		{"{{end}}"},
+	}, func(f *excelize.File) {
+		f.SetPanes("Sheet1", `{"freeze":true,"split":false,"x_split":0,"y_split":1,"top_left_cell":"A2","active_pane":"bottomLeft"}`)
+		f.SetSheetViewOptions("Sheet1", 0, excelize.ZoomScale(80), excelize.ShowGridLines(false))
+		f.AutoFilter("Sheet1", "A1", "B1", "")
+	}, map[string]interface{}{
+		"items": []map[string]interface{}{
+			{"name": "a", "age": 1},
+			{"name": "b", "age": 2},
+			{"name": "c", "age": 3},
+		},
+	}, [][]string{
+		{"Name", "Age"},
+		{"a", "1"},
+		{"b", "2"},
+		{"c", "3"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		zoom      excelize.ZoomScale
+		gridLines excelize.ShowGridLines
+	)
+	if err = rf.GetSheetViewOptions("Sheet1", 0, &zoom, &gridLines); err != nil {
+		t.Fatal(err)
+	}
+	if zoom != 80 || gridLines {
+		t.Errorf("Sheet view = (%v, %v), want (80, false)", zoom, gridLines)
+	}
+	ws := rf.Sheet[worksheetPath(rf, "Sheet1")]
+	if pane := ws.SheetViews.SheetView[0].Pane; pane == nil || pane.State != "frozen" || pane.TopLeftCell != "A2" {
+		t.Errorf("Pane = %v, want frozen at A2", pane)
+	}
+	if ws.AutoFilter == nil || ws.AutoFilter.Ref != "A1:B4" {
+		t.Errorf("Auto filter = %v, want A1:B4", ws.AutoFilter)
+	}
+	var filterRange string
+	for _, dn := range rf.GetDefinedName() {
+		if dn.Name == "_xlnm._FilterDatabase" {
+			filterRange = dn.RefersTo
+		}
+	}
+	if filterRange != "Sheet1!A1:B4" {
+		t.Errorf("Filter database = %s, want Sheet1!A1:B4", filterRange)
+	}
+}
+
+func Test_RenderTable(t *testing.T) {
+	rf, err := renderExcelHelper([][]string{
+		{"Name", "Amount", "Double"},
+		{"{{range items}}"},
+		{"{{name}}", "{{amount}}"},
+		{"{{end}}"},
+		{"Total"},
+	}, func(f *excelize.File) {
+		f.AddTable("Sheet1", "A1", "C5", `{"table_name":"Items","table_style":"TableStyleMedium2"}`)
+		// excelize can't add totals row and calculated column
+		table := string(f.XLSX["xl/tables/table1.xml"])
+		table = strings.Replace(table, `totalsRowShown="false"`, `totalsRowCount="1"`, 1)
+		table = strings.Replace(table, `<autoFilter ref="A1:C5">`, `<autoFilter ref="A1:C4">`, 1)
+		table = strings.Replace(table, `name="Amount">`, `name="Amount" totalsRowFunction="sum">`, 1)
+		table = strings.Replace(table, `name="Double">`, `name="Double"><calculatedColumnFormula>Items[[#This Row],[Amount]]*2</calculatedColumnFormula>`, 1)
+		f.XLSX["xl/tables/table1.xml"] = []byte(table)
+	}, map[string]interface{}{
+		"items": []map[string]interface{}{
+			{"name": "a", "amount": 1},
+			{"name": "b", "amount": 2},
+			{"name": "c", "amount": 3},
+			{"name": "d", "amount": 4},
+		},
+	}, [][]string{
+		{"Name", "Amount", "Double"},
+		{"a", "1", ""},
+		{"b", "2", ""},
+		{"c", "3", ""},
+		{"d", "4", ""},
+		{"Total", ""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := string(rf.XLSX["xl/tables/table1.xml"])
+	for _, want := range []string{`name="Items"`, `ref="A1:C6"`, `<autoFilter ref="A1:C5"`, `TableStyleMedium2`} {
+		if !strings.Contains(table, want) {
+			t.Errorf("Table = %s, want contains %s", table, want)
+		}
+	}
+	for axis, want := range map[string]string{
+		"C2": "Items[[#This Row],[Amount]]*2",
+		"C5": "Items[[#This Row],[Amount]]*2",
+		"B6": "SUBTOTAL(109,Items[Amount])",
+	} {
+		if got, _ := rf.GetCellFormula("Sheet1", axis); got != want {
+			t.Errorf("Formula of %s = %s, want %s", axis, got, want)
+		}
+	}
+}
+
+func Test_RenderGroup(t *testing.T) {
+	rf, err := renderExcelHelper([][]string{
+		{"{{group staff by dept outline}}"},
+		{"{{dept}}"},
+		{"{{range items}}"},
+		{"", "{{name}}", "{{salary}}"},
+		{"{{end}}"},
+		{"Subtotal", "{{count}}", "{{sum.salary}}", "{{max.salary}}"},
+		{"{{end}}"},
+		{"Total"},
+	}, nil, map[string]interface{}{
+		"staff": []map[string]interface{}{
+			{"dept": "A", "name": "a", "salary": 1},
+			{"dept": "B", "name": "b", "salary": 2},
+			{"dept": "A", "name": "c", "salary": 3.5},
+		},
+	}, [][]string{
+		{"A"},
+		{"", "a", "1"},
+		{"", "c", "3.5"},
+		{"Subtotal", "2", "4.5", "3.5"},
+		{"B"},
+		{"", "b", "2"},
+		{"Subtotal", "1", "2", "2"},
+		{"Total"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for row, want := range []uint8{0, 1, 1, 0, 0, 1, 0, 0} {
+		if level, _ := rf.GetRowOutlineLevel("Sheet1", row+1); level != want {
+			t.Errorf("Outline level of row %d = %d, want %d", row+1, level, want)
+		}
+	}
+}
+
+func Test_RenderGroupKeyPath(t *testing.T) {
+	_, err := renderExcelHelper([][]string{
+		{"{{group report.staff by dept}}"},
+		{"{{dept}}", "{{count}}"},
+		{"{{end}}"},
+	}, nil, map[string]interface{}{
+		"report": map[string]interface{}{"staff": []map[string]interface{}{
+			{"dept": "A"}, {"dept": "B"}, {"dept": "A"},
+		}},
+	}, [][]string{{"A", "2"}, {"B", "1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_RenderAggregate(t *testing.T) {
+	type product struct {
+		Price float64 `json:"price"`
+	}
+	items := []map[string]interface{}{
+		{"price": 1, "product": map[string]interface{}{"price": 10}},
+		{"price": 2.5, "product": map[string]interface{}{"price": 5}},
+		{"price": "n/a", "product": map[string]interface{}{"price": 20}},
+		{"price": 4},
+	}
+	_, err := renderExcelHelper([][]string{
+		{"{{count items}}", "{{sum items \"price\"}}", "{{avg items \"price\"}}", "{{min items \"product.price\"}}", "{{max items \"product.price\"}}"},
+		{"{{count empty}}", "{{sum empty \"price\"}}", "{{avg empty \"price\"}}", "{{min none \"price\"}}", "{{max none \"price\"}}"},
+		{"{{sum products \"Price\"}}", "{{max products \"price\"}}", "{{avg ptrs \"Price\"}}"},
+	}, nil, map[string]interface{}{
+		"items":    items,
+		"empty":    []product{},
+		"products": []product{{Price: 1.5}, {Price: 2}},
+		"ptrs":     []*product{{Price: 1}, nil, {Price: 3}},
+	}, [][]string{
+		{"4", "7.5", "2.5", "5", "20"},
+		{"0", "0", "0", "0", "0"},
+		{"3.5", "2", "2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string]interface{}{
+		"map":          map[string]interface{}{"price": 1},
+		"chan":         map2MapChanHelper([]map[string]interface{}{{"price": 1}}),
+		"scalar items": []int{1, 2},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := renderExcelHelper([][]string{
+				{"{{sum items \"price\"}}"},
+			}, nil, map[string]interface{}{"items": data}, nil)
+			if err == nil {
+				t.Errorf("Render %s should be error", name)
+			}
+		})
+	}
+}
+
+func Test_RenderNestedScope(t *testing.T) {
+	_, err := renderExcelHelper([][]string{
+		{"{{range $o := orders}}"},
+		{"{{name}}"},
+		{"{{range $o.lines}}"},
+		{"{{name}}", "{{$o.name}}", "{{$parent.name}}", "{{$root.name}}", "{{title}}"},
+		{"{{end}}"},
+		{"{{range lines}}"},
+		{"{{$parent.$parent.name}}"},
+		{"{{end}}"},
+		{"{{end}}"},
+	}, nil, map[string]interface{}{
+		"title": "T",
+		"name":  "root",
+		"orders": []map[string]interface{}{
+			{"name": "o1", "lines": []map[string]interface{}{{"name": "l1"}, {"name": "l2"}}},
+			{"name": "o2", "lines": []map[string]interface{}{{"name": "l3"}}},
+		},
+	}, [][]string{
+		{"o1"},
+		{"l1", "o1", "o1", "root", "T"},
+		{"l2", "o1", "o1", "root", "T"},
+		{"root"},
+		{"root"},
+		{"o2"},
+		{"l3", "o2", "o2", "root", "T"},
+		{"root"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_RenderLoopMeta(t *testing.T) {
+	rows := []map[string]interface{}{{"name": "a"}, {"name": "b"}, {"name": "c"}}
+	temp := [][]string{
+		{"{{range rows}}"},
+		{"{{$number}}", "{{$index}}", "{{$first}}", "{{$last}}", "{{$even}}", "{{$odd}}", "{{$count}}", "{{name}}"},
+		{"{{end}}"},
+	}
+	expect := [][]string{
+		{"1", "0", "true", "false", "true", "false", "3", "a"},
+		{"2", "1", "false", "false", "false", "true", "3", "b"},
+		{"3", "2", "false", "true", "true", "false", "3", "c"},
+	}
+	for name, data := range map[string]interface{}{
+		"slice": rows,
+		"chan":  map2MapChanHelper(rows),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := renderExcelHelper(temp, nil, map[string]interface{}{"rows": data}, expect); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func Test_RenderRangeCollection(t *testing.T) {
+	tests := []struct {
+		name    string
+		temp    [][]string
+		data    map[string]interface{}
+		expect  [][]string
+		wantErr error
+	}{
+		{
+			name: "slice of scalar",
+			temp: [][]string{
+				{"{{range tags}}"},
+				{"{{.}}", "{{$value}}", "{{$number}}"},
+				{"{{end}}"},
+			},
+			data:   map[string]interface{}{"tags": []string{"a", "b"}},
+			expect: [][]string{{"a", "a", "1"}, {"b", "b", "2"}},
+		},
+		{
+			name: "chan of scalar",
+			temp: [][]string{
+				{"{{range tags}}"},
+				{"{{.}}"},
+				{"{{end}}"},
+			},
+			data:   map[string]interface{}{"tags": map2InterChanHelper([]interface{}{1, "b"})},
+			expect: [][]string{{"1"}, {"b"}},
+		},
+		{
+			name: "map sorted by key",
+			temp: [][]string{
+				{"{{range scores}}"},
+				{"{{$key}}", "{{$value}}"},
+				{"{{end}}"},
+			},
+			data:   map[string]interface{}{"scores": map[string]int{"c": 3, "a": 1, "b": 2}},
+			expect: [][]string{{"a", "1"}, {"b", "2"}, {"c", "3"}},
+		},
+		{
+			name: "map sorted by number key",
+			temp: [][]string{
+				{"{{range scores}}"},
+				{"{{$key}}", "{{$value}}"},
+				{"{{end}}"},
+			},
+			data:   map[string]interface{}{"scores": map[int]string{10: "j", 2: "b"}},
+			expect: [][]string{{"2", "b"}, {"10", "j"}},
+		},
+		{
+			name: "map of map",
+			temp: [][]string{
+				{"{{range users}}"},
+				{"{{$key}}", "{{name}}"},
+				{"{{end}}"},
+			},
+			data: map[string]interface{}{"users": map[string]interface{}{
+				"u2": map[string]interface{}{"name": "b"},
+				"u1": map[string]interface{}{"name": "a"},
+			}},
+			expect: [][]string{{"u1", "a"}, {"u2", "b"}},
+		},
+		{
+			name: "slice of struct",
+			temp: [][]string{
+				{"{{range users}}"},
+				{"{{Name}}", "{{age}}"},
+				{"{{end}}"},
+			},
+			data: map[string]interface{}{"users": []struct {
+				Name string
+				Age  int `json:"age"`
+			}{{"a", 1}, {"b", 2}}},
+			expect: [][]string{{"a", "1"}, {"b", "2"}},
+		},
+		{
+			name: "slice of pointer",
+			temp: [][]string{
+				{"{{range users}}"},
+				{"{{name}}"},
+				{"{{end}}"},
+			},
+			data:   map[string]interface{}{"users": []*map[string]string{{"name": "a"}, {"name": "b"}}},
+			expect: [][]string{{"a"}, {"b"}},
+		},
+		{
+			name: "slice of time",
+			temp: [][]string{
+				{"{{range days}}"},
+				{"{{.}}"},
+				{"{{end}}"},
+			},
+			data:   map[string]interface{}{"days": []time.Time{time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)}},
+			expect: [][]string{{"2020-01-02T00:00:00Z"}},
+		},
+		{
+			name: "not collection",
+			temp: [][]string{
+				{"{{range name}}"},
+				{"{{.}}"},
+				{"{{end}}"},
+			},
+			data:    map[string]interface{}{"name": "a"},
+			wantErr: NotRangeValue,
+		},
+		{
+			name: "item not string key map",
+			temp: [][]string{
+				{"{{range rows}}"},
+				{"{{.}}"},
+				{"{{end}}"},
+			},
+			data:    map[string]interface{}{"rows": []interface{}{map[string]int{"a": 1}, map[int]int{1: 1}}},
+			wantErr: NotStringKeyMapValue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := renderExcelHelper(tt.temp, nil, tt.data, tt.expect)
+			if err != tt.wantErr {
+				t.Errorf("Render error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_RenderRangeClause(t *testing.T) {
+	orders := []map[string]interface{}{
+		{"id": 1, "status": "open", "date": "2020-01-03", "amount": 30},
+		{"id": 2, "status": "closed", "date": "2020-01-01", "amount": 10},
+		{"id": 3, "status": "open", "date": "2020-01-02", "amount": 20},
+		{"id": 4, "status": "open", "date": "2020-01-02", "amount": 40},
+	}
+	tests := []struct {
+		name    string
+		temp    [][]string
+		data    map[string]interface{}
+		expect  [][]string
+		wantErr bool
+	}{
+		{
+			name: "where",
+			temp: [][]string{
+				{`{{range orders where status == "open"}}`},
+				{"{{id}}", "{{$count}}"},
+				{"{{end}}"},
+			},
+			data:   map[string]interface{}{"orders": orders},
+			expect: [][]string{{"1", "3"}, {"3", "3"}, {"4", "3"}},
+		},
+		{
+			name: "where with named item and expression",
+			temp: [][]string{
+				{`{{range $o := orders where $o.amount >= min && status != "closed"}}`},
+				{"{{$o.id}}"},
+				{"{{end}}"},
+			},
+			data:   map[string]interface{}{"orders": orders, "min": 25},
+			expect: [][]string{{"1"}, {"4"}},
+		},
+		{
+			name: "where boolean and number field",
+			temp: [][]string{
+				{"{{range users where active}}"},
+				{"{{name}}"},
+				{"{{end}}"},
+				{"{{range users where score}}"},
+				{"{{name}}"},
+				{"{{end}}"},
+			},
+			data: map[string]interface{}{"users": []map[string]interface{}{
+				{"name": "a", "active": true, "score": 0},
+				{"name": "b", "active": false, "score": 1},
+			}},
+			expect: [][]string{{"a"}, {"b"}},
+		},
+		{
+			name: "sortBy desc",
+			temp: [][]string{
+				{"{{range orders sortBy date desc, amount}}"},
+				{"{{id}}"},
+				{"{{end}}"},
+			},
+			data:   map[string]interface{}{"orders": orders},
+			expect: [][]string{{"1"}, {"3"}, {"4"}, {"2"}},
+		},
+		{
+			name: "where sortBy limit",
+			temp: [][]string{
+				{`{{range orders where status == "open" sortBy date desc limit 2}}`},
+				{"{{id}}", "{{$last}}"},
+				{"{{end}}"},
+			},
+			data:   map[string]interface{}{"orders": orders},
+			expect: [][]string{{"1", "false"}, {"3", "true"}},
+		},
+		{
+			name: "limit by key",
+			temp: [][]string{
+				{"{{range orders sortBy amount limit top}}"},
+				{"{{amount}}"},
+				{"{{end}}"},
+			},
+			data:   map[string]interface{}{"orders": orders, "top": 1},
+			expect: [][]string{{"10"}},
+		},
+		{
+			name: "sortBy scalar",
+			temp: [][]string{
+				{"{{range tags sortBy . desc}}"},
+				{"{{.}}"},
+				{"{{end}}"},
+			},
+			data:   map[string]interface{}{"tags": map2InterChanHelper([]interface{}{"a", "c", "b"})},
+			expect: [][]string{{"c"}, {"b"}, {"a"}},
+		},
+		{
+			name: "same data feeds several sections",
+			temp: [][]string{
+				{`{{range orders where status == "closed"}}`},
+				{"{{id}}"},
+				{"{{end}}"},
+				{"{{range orders limit 1}}"},
+				{"{{id}}"},
+				{"{{end}}"},
+			},
+			data:   map[string]interface{}{"orders": orders},
+			expect: [][]string{{"2"}, {"1"}},
+		},
+		{
+			name: "invalid sortBy",
+			temp: [][]string{
+				{"{{range orders sortBy date up}}"},
+				{"{{id}}"},
+				{"{{end}}"},
+			},
+			data:    map[string]interface{}{"orders": orders},
+			wantErr: true,
+		},
+		{
+			name: "unknown clause",
+			temp: [][]string{
+				{"{{range orders having id}}"},
+				{"{{id}}"},
+				{"{{end}}"},
+			},
+			data:    map[string]interface{}{"orders": orders},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := renderExcelHelper(tt.temp, nil, tt.data, tt.expect)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Render error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_RenderWith(t *testing.T) {
+	customer := map[string]interface{}{
+		"name": "Tom",
+		"address": map[string]interface{}{
+			"city": "Chengdu",
+			"zip":  "610000",
+		},
+	}
+	tests := []struct {
+		name   string
+		temp   [][]string
+		data   map[string]interface{}
+		expect [][]string
+	}{
+		{
+			name: "scope into nested object",
+			temp: [][]string{
+				{"{{customer.name}}"},
+				{"{{with customer.address}}"},
+				{"{{city}}", "{{zip}}", "{{$parent.customer.name}}"},
+				{"{{end}}"},
+				{"end"},
+			},
+			data:   map[string]interface{}{"customer": customer},
+			expect: [][]string{{"Tom"}, {"Chengdu", "610000", "Tom"}, {"end"}},
+		},
+		{
+			name: "skipped when empty",
+			temp: [][]string{
+				{"{{with customer.phone}}"},
+				{"{{.}}"},
+				{"{{end}}"},
+				{"{{with tags}}"},
+				{"{{.}}"},
+				{"{{end}}"},
+				{"end"},
+			},
+			data:   map[string]interface{}{"customer": customer, "tags": []string{}},
+			expect: [][]string{{"end"}},
+		},
+		{
+			name: "scalar",
+			temp: [][]string{
+				{"{{with customer.name}}"},
+				{"{{.}}"},
+				{"{{end}}"},
+			},
+			data:   map[string]interface{}{"customer": customer},
+			expect: [][]string{{"Tom"}},
+		},
+		{
+			name: "nested in range",
+			temp: [][]string{
+				{"{{range customers}}"},
+				{"{{with address}}"},
+				{"{{$parent.name}}", "{{city}}"},
+				{"{{end}}"},
+				{"{{end}}"},
+			},
+			data: map[string]interface{}{"customers": []map[string]interface{}{
+				customer,
+				{"name": "Jerry"},
+			}},
+			expect: [][]string{{"Tom", "Chengdu"}},
+		},
+		{
+			name: "struct",
+			temp: [][]string{
+				{"{{c.Name}}", "{{c.Tags[1]}}"},
+				{"{{with c.Addr}}"},
+				{"{{City}}", "{{zip}}"},
+				{"{{end}}"},
+				{"{{range cs}}"},
+				{"{{Addr.City}}"},
+				{"{{end}}"},
+				{`{{sum cs "Addr.Zip"}}`},
+			},
+			data: map[string]interface{}{
+				"c":  testCustomer{Name: "Tom", Addr: testAddr{City: "Chengdu", Zip: 1}, Tags: []string{"a", "b"}},
+				"cs": []*testCustomer{{Addr: testAddr{City: "Chengdu", Zip: 1}}, {Addr: testAddr{City: "Beijing", Zip: 2}}},
+			},
+			expect: [][]string{{"Tom", "b"}, {"Chengdu", "1"}, {"Chengdu"}, {"Beijing"}, {"3"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := renderExcelHelper(tt.temp, nil, tt.data, tt.expect); err != nil {
+				t.Errorf("Render error = %v", err)
+			}
+		})
+	}
+	t.Run("without end", func(t *testing.T) {
+		_, err := renderExcelHelper([][]string{{"{{with customer}}"}, {"{{name}}"}}, nil, map[string]interface{}{"customer": customer}, nil)
+		if err != NotMatchRangeEnd {
+			t.Errorf("Render error = %v, want %v", err, NotMatchRangeEnd)
+		}
+	})
+}
+
+func Test_RenderAssign(t *testing.T) {
+	items := []map[string]interface{}{
+		{"name": "a", "amount": 10},
+		{"name": "b", "amount": 30},
+	}
+	tests := []struct {
+		name    string
+		temp    [][]string
+		data    map[string]interface{}
+		expect  [][]string
+		wantErr bool
+	}{
+		{
+			name: "variables row",
+			temp: [][]string{
+				{`{{$total := sum items "amount"}}`, "{{$n := count items}}"},
+				{"{{$total}}", "{{$total / $n}}"},
+			},
+			data:   map[string]interface{}{"items": items},
+			expect: [][]string{{"40", "20"}},
+		},
+		{
+			name: "variable cell",
+			temp: [][]string{
+				{"{{$rate := 2}}", "{{$rate * 3}}"},
+				{"{{$rate}}"},
+			},
+			expect: [][]string{{"", "6"}, {"2"}},
+		},
+		{
+			name: "string literal",
+			temp: [][]string{
+				{`{{$x := "text"}}`, `{{$y := "a \"b\""}}`, `{{$z := ""}}`},
+				{"{{$x}}", "{{$y}}", "{{$z}}{{$x}}"},
+			},
+			expect: [][]string{{"text", `a "b"`, "text"}},
+		},
+		{
+			name: "visible in block",
+			temp: [][]string{
+				{`{{$total := sum items "amount"}}`},
+				{"{{range items}}"},
+				{"{{name}}", "{{amount * 100 / $total}}"},
+				{"{{end}}"},
+			},
+			data:   map[string]interface{}{"items": items},
+			expect: [][]string{{"a", "25"}, {"b", "75"}},
+		},
+		{
+			name: "scoped in block",
+			temp: [][]string{
+				{"{{$x := 1}}"},
+				{"{{range items}}"},
+				{"{{$x := amount}}"},
+				{"{{$x}}"},
+				{"{{end}}"},
+				{"{{$x}}"},
+			},
+			data:   map[string]interface{}{"items": items},
+			expect: [][]string{{"10"}, {"30"}, {"1"}},
+		},
+		{
+			name: "invalid expression",
+			temp: [][]string{
+				{"{{$x := 1 +}}"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := renderExcelHelper(tt.temp, nil, tt.data, tt.expect)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Render error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_RenderInclude(t *testing.T) {
+	header := func(f *excelize.File) {
+		f.NewSheet("Header")
+		f.SetCellValue("Header", "A1", "{{title}}")
+		f.SetCellValue("Header", "A2", "{{range tags}}")
+		f.SetCellValue("Header", "A3", "{{.}}")
+		f.SetCellValue("Header", "A4", "{{end}}")
+		f.SetSheetVisible("Header", false)
+	}
+	footer := excelize.NewFile()
+	footer.SetSheetName("Sheet1", "Footer")
+	footer.SetCellValue("Footer", "A1", "{{$n := count tags}}")
+	footer.SetCellValue("Footer", "A2", "total")
+	footer.SetCellValue("Footer", "B2", "{{$n}}")
+	fb, err := footer.WriteToBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := map[string]interface{}{"title": "Report", "tags": []string{"a", "b"}}
+
+	tests := []struct {
+		name    string
+		temp    [][]string
+		prepare func(f *excelize.File)
+		data    interface{}
+		expect  [][]string
+		sheets  []string
+		names   []string
+		wantErr error
+	}{
+		{
+			name: "include hidden sheet and partial",
+			temp: [][]string{
+				{`{{include "Header"}}`},
+				{"body"},
+				{`{{include "Footer"}}`},
+				{"{{$n}}"},
+			},
+			prepare: header,
+			data:    data,
+			expect:  [][]string{{"Report"}, {"a"}, {"b"}, {"body"}, {"total", "2"}, {""}},
+			sheets:  []string{"Sheet1"},
+		},
+		{
+			name: "include in range scope",
+			temp: [][]string{
+				{"{{range rows}}"},
+				{`{{include "Header"}}`},
+				{"{{end}}"},
+			},
+			prepare: header,
+			data: map[string]interface{}{"rows": []map[string]interface{}{
+				{"title": "t1", "tags": []string{"a"}},
+				{"title": "t2"},
+			}},
+			expect: [][]string{{"t1"}, {"a"}, {"t2"}},
+			sheets: []string{"Sheet1"},
+		},
+		{
+			name: "visible sheet is kept",
+			temp: [][]string{
+				{`{{include "Header"}}`},
+			},
+			prepare: func(f *excelize.File) {
+				header(f)
+				f.SetSheetVisible("Header", true)
+			},
+			data:   data,
+			expect: [][]string{{"Report"}, {"a"}, {"b"}},
+			sheets: []string{"Sheet1", "Header"},
+		},
+		{
+			name: "names referring to hidden sheet are dropped",
+			temp: [][]string{
+				{`{{include "Header"}}`},
+			},
+			prepare: func(f *excelize.File) {
+				header(f)
+				f.SetDefinedName(&excelize.DefinedName{Name: "Title", RefersTo: "Header!$A$1"})
+				f.SetDefinedName(&excelize.DefinedName{Name: "Both", RefersTo: "Sheet1!$A$1+'Header'!$A$3"})
+				f.SetDefinedName(&excelize.DefinedName{Name: "Const", RefersTo: "3"})
+			},
+			data:   data,
+			expect: [][]string{{"Report"}, {"a"}, {"b"}},
+			sheets: []string{"Sheet1"},
+			names:  []string{"Const"},
+		},
+		{
+			name: "not found",
+			temp: [][]string{
+				{`{{include "Nothing"}}`},
+			},
+			data:    data,
+			wantErr: NotFoundPartial,
+		},
+		{
+			name: "include itself",
+			temp: [][]string{
+				{`{{include "Sheet1"}}`},
+			},
+			data:    data,
+			wantErr: IncludeCycle,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tf := excelize.NewFile()
+			for j, item := range tt.temp {
+				for k, it := range item {
+					n, _ := excelize.CoordinatesToCellName(k+1, j+1)
+					tf.SetCellValue("Sheet1", n, it)
+				}
+			}
+			if tt.prepare != nil {
+				tt.prepare(tf)
+			}
+			bf, err := tf.WriteToBuffer()
+			if err != nil {
+				t.Fatal(err)
+			}
+			xl, err := NewFromBinary(bf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = xl.RegisterPartial(fb.Bytes()); err != nil {
+				t.Fatal(err)
+			}
+			if err = xl.Render(nil, tt.data); err != tt.wantErr {
+				t.Fatalf("Render error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			result := xl.Result()
+			if err = checkExcelHelper(result.Bytes(), tt.expect); err != nil {
+				t.Fatal(err)
+			}
+			rf, _ := excelize.OpenReader(bytes.NewReader(result.Bytes()))
+			if got := rf.GetSheetList(); !reflect.DeepEqual(got, tt.sheets) {
+				t.Errorf("Sheets = %v, want %v", got, tt.sheets)
+			}
+			if tt.names == nil {
+				return
+			}
+			var names []string
+			for _, dn := range rf.GetDefinedName() {
+				names = append(names, dn.Name)
+			}
+			if !reflect.DeepEqual(names, tt.names) {
+				t.Errorf("Defined names = %v, want %v", names, tt.names)
+			}
+		})
+	}
+}
+
+func Test_RenderIncludeStyleAndMerge(t *testing.T) {
+	rf, err := renderExcelHelper([][]string{
+		{"top"},
+		{`{{include "P"}}`},
+		{"bottom"},
+	}, func(f *excelize.File) {
+		f.NewSheet("P")
+		f.SetCellValue("P", "A1", "{{title}}")
+		f.SetCellValue("P", "A2", "{{range tags}}")
+		f.SetCellValue("P", "A3", "{{.}}")
+		f.SetCellValue("P", "A4", "{{end}}")
+		f.MergeCell("P", "A1", "C1")
+		f.MergeCell("P", "A3", "B3")
+		bold, _ := f.NewStyle(`{"font":{"bold":true},"number_format":0,"custom_number_format":"0.00%"}`)
+		f.SetCellStyle("P", "A1", "A1", bold)
+		f.SetSheetVisible("P", false)
+	}, map[string]interface{}{"title": "Report", "tags": []string{"a", "b"}}, [][]string{
+		{"top"},
+		{"Report"},
+		{"a"},
+		{"b"},
+		{"bottom"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var merges []string
+	mcs, _ := rf.GetMergeCells("Sheet1")
+	for _, mc := range mcs {
+		merges = append(merges, mc.GetStartAxis()+":"+mc.GetEndAxis())
+	}
+	if want := []string{"A2:C2", "A3:B3", "A4:B4"}; !reflect.DeepEqual(merges, want) {
+		t.Errorf("Merges = %v, want %v", merges, want)
+	}
+	id, _ := rf.GetCellStyle("Sheet1", "A2")
+	if id == 0 {
+		t.Fatal("Style of A2 should be copied from partial")
+	}
+	xf := rf.Styles.CellXfs.Xf[id]
+	if font := rf.Styles.Fonts.Font[*xf.FontID]; font.B == nil || !*font.B {
+		t.Errorf("Font of A2 should be bold")
+	}
+	if xf.NumFmtID == nil || *xf.NumFmtID < 164 {
+		t.Fatalf("Number format of A2 should be custom")
+	}
+	for _, nf := range rf.Styles.NumFmts.NumFmt {
+		if nf.NumFmtID == *xf.NumFmtID && nf.FormatCode != "0.00%" {
+			t.Errorf("Number format of A2 = %s, want 0.00%%", nf.FormatCode)
+		}
+	}
+	if id, _ := rf.GetCellStyle("Sheet1", "A3"); id != 0 {
+		t.Errorf("Style of A3 = %d, want 0", id)
+	}
+}
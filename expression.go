@@ -0,0 +1,385 @@
+package xlsxt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var typeOfFloat64 = reflect.TypeOf(float64(0))
+
+// precedences of binary operators, the bigger one binds tighter.
+var precedences = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3, "<": 3, "<=": 3, ">": 3, ">=": 3,
+	"+": 4, "-": 4,
+	"*": 5, "/": 5, "%": 5,
+}
+
+// operation is the binary operation in expression like `{{price * qty}}`.
+type operation struct {
+	op   string
+	l, r *parm
+	// key is the token written without spaces like `a-b`,
+	// which is a key when it's in data.
+	key string
+}
+
+func isOperator(p parm) bool {
+	if p.t != key {
+		return false
+	}
+	_, in := precedences[p.v.(string)]
+	return in
+}
+
+func isSymbol(p parm, symbol string) bool {
+	return p.t == key && p.v == symbol
+}
+
+// hasOperator reports whether the params is an expression.
+func hasOperator(ps []parm) bool {
+	for _, p := range ps {
+		if isOperator(p) || p.t == key && strings.ContainsAny(p.v.(string), "()") {
+			return true
+		}
+	}
+	return false
+}
+
+// splitOperators parses the keys with operators like `a*b` to expression,
+// the expression is evaluated only when the key isn't in data, so the key like
+// `first-name` still works.
+func splitOperators(ps []parm) []parm {
+	result := make([]parm, 0, len(ps))
+	for _, p := range ps {
+		s, _ := p.v.(string)
+		if p.t != key || numberRgx.MatchString(s) {
+			result = append(result, p)
+			continue
+		}
+		pieces := splitOperatorPieces(p, s)
+		if len(pieces) < 2 {
+			result = append(result, pieces...)
+			continue
+		}
+		e, err := newExpression(pieces)
+		if err != nil || e.t != expression {
+			// like `(a-b` in `(a-b + c)`, parsed with others
+			result = append(result, pieces...)
+			continue
+		}
+		e.v.(*operation).key = s
+		result = append(result, *e)
+	}
+	return result
+}
+
+// splitOperatorPieces splits the operators out of key s, like `a*b` to `a`, `*`
+// and `b`, the operators in bracket or quote of key path and the sign of number
+// like `-1` are kept.
+func splitOperatorPieces(p parm, s string) (result []parm) {
+	var (
+		begin, depth int
+		inQuote      bool
+	)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && inQuote:
+			i++
+		case c == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case depth == 0:
+			op := operatorAt(s, i)
+			if op == "" {
+				continue
+			}
+			if begin < i {
+				t, v := literal(s[begin:i])
+				result = append(result, parm{t: t, v: v})
+			}
+			result = append(result, parm{t: key, v: op})
+			i += len(op) - 1
+			begin = i + 1
+		}
+	}
+	if begin == 0 {
+		result = append(result, p)
+	} else if begin < len(s) {
+		t, v := literal(s[begin:])
+		result = append(result, parm{t: t, v: v})
+	}
+	return
+}
+
+// operatorAt returns the longest operator begins at i of s.
+func operatorAt(s string, i int) string {
+	if i+1 < len(s) {
+		if _, in := precedences[s[i:i+2]]; in {
+			return s[i : i+2]
+		}
+	}
+	if _, in := precedences[s[i:i+1]]; in {
+		return s[i : i+1]
+	}
+	return ""
+}
+
+// splitParens splits the parentheses around keys, like `(price` to `(` and `price`.
+func splitParens(ps []parm) []parm {
+	result := make([]parm, 0, len(ps))
+	for _, p := range ps {
+		if p.t != key {
+			result = append(result, p)
+			continue
+		}
+		s := p.v.(string)
+		for strings.HasPrefix(s, "(") {
+			result = append(result, parm{t: key, v: "("})
+			s = s[1:]
+		}
+		var closes int
+		for strings.HasSuffix(s, ")") {
+			closes++
+			s = s[:len(s)-1]
+		}
+		if s != "" {
+			t, v := literal(s)
+			result = append(result, parm{t: t, v: v})
+		}
+		for ; closes > 0; closes-- {
+			result = append(result, parm{t: key, v: ")"})
+		}
+	}
+	return result
+}
+
+// newExpression parses the params to expression with standard precedence and
+// parentheses, a helper with params could be an operand like `sum items "price" * 2`.
+func newExpression(ps []parm) (*parm, error) {
+	ep := &exprParser{ps: splitParens(ps)}
+	p, err := ep.parse(1)
+	if err != nil {
+		return nil, err
+	}
+	if ep.i < len(ep.ps) {
+		return nil, fmt.Errorf("Unexpected `%v` in expression.", ep.ps[ep.i].v)
+	}
+	return p, nil
+}
+
+type exprParser struct {
+	ps []parm
+	i  int
+}
+
+func (ep *exprParser) parse(minPrec int) (*parm, error) {
+	l, err := ep.primary()
+	if err != nil {
+		return nil, err
+	}
+	for ep.i < len(ep.ps) && isOperator(ep.ps[ep.i]) {
+		op := ep.ps[ep.i].v.(string)
+		prec := precedences[op]
+		if prec < minPrec {
+			break
+		}
+		ep.i++
+		r, err := ep.parse(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		l = &parm{t: expression, v: &operation{op: op, l: l, r: r}}
+	}
+	return l, nil
+}
+
+// isOperand reports whether the next param is an operand.
+func (ep *exprParser) isOperand() bool {
+	return ep.i < len(ep.ps) && !isOperator(ep.ps[ep.i]) && !isSymbol(ep.ps[ep.i], ")")
+}
+
+func (ep *exprParser) primary() (*parm, error) {
+	if ep.i >= len(ep.ps) {
+		return nil, errors.New("Expression without operand.")
+	}
+	p := ep.ps[ep.i]
+	ep.i++
+	switch {
+	case isSymbol(p, "("):
+		e, err := ep.parse(1)
+		if err != nil {
+			return nil, err
+		}
+		if ep.i >= len(ep.ps) || !isSymbol(ep.ps[ep.i], ")") {
+			return nil, errors.New("Expression without `)`.")
+		}
+		ep.i++
+		return e, nil
+	case isSymbol(p, "-"):
+		// unary minus
+		r, err := ep.primary()
+		if err != nil {
+			return nil, err
+		}
+		return &parm{t: expression, v: &operation{op: "-", l: &parm{t: general, v: 0}, r: r}}, nil
+	case isOperator(p), isSymbol(p, ")"):
+		return nil, fmt.Errorf("Unexpected `%v` in expression.", p.v)
+	}
+
+	if p.t != key || !ep.isOperand() {
+		return &p, nil
+	}
+	// helper with params
+	var args []parm
+	for ep.isOperand() {
+		arg := ep.ps[ep.i]
+		ep.i++
+		if isSymbol(arg, "(") {
+			ep.i--
+			a, err := ep.primary()
+			if err != nil {
+				return nil, err
+			}
+			arg = *a
+		}
+		args = append(args, arg)
+	}
+	return newFuncParm(p.v.(string), args, nil)
+}
+
+func (o *operation) exec(ctx context.Context, in map[string]interface{}) (interface{}, error) {
+	if o.key != "" {
+		if v, err := lookupPath(in, o.key); err == nil && v != nil {
+			return v, nil
+		}
+	}
+	l, err := o.l.exec(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	l, _ = unwrapCellValue(l, nil)
+	switch o.op {
+	case "&&", "||":
+		// short circuit
+		if truthy(l) == (o.op == "||") {
+			return truthy(l), nil
+		}
+	}
+	r, err := o.r.exec(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	r, _ = unwrapCellValue(r, nil)
+
+	switch o.op {
+	case "&&", "||":
+		return truthy(r), nil
+	case "==", "!=", "<", "<=", ">", ">=":
+		return compare(o.op, l, r), nil
+	}
+
+	lf, li, err := toNumber(l)
+	if err != nil {
+		return nil, err
+	}
+	rf, ri, err := toNumber(r)
+	if err != nil {
+		return nil, err
+	}
+	isInt := li && ri
+	switch o.op {
+	case "+":
+		lf += rf
+	case "-":
+		lf -= rf
+	case "*":
+		lf *= rf
+	case "/":
+		if rf == 0 {
+			return nil, errors.New("Division by zero.")
+		}
+		return lf / rf, nil
+	case "%":
+		if rf == 0 {
+			return nil, errors.New("Division by zero.")
+		}
+		lf = math.Mod(lf, rf)
+	}
+	if isInt {
+		return int64(lf), nil
+	}
+	return lf, nil
+}
+
+// toNumber converts v to float64, and reports whether v is an integer.
+func toNumber(v interface{}) (f float64, isInt bool, err error) {
+	rv, err := interface2AppointType(v, typeOfFloat64)
+	if err != nil {
+		return 0, false, fmt.Errorf("%v couldn't convert to number.", v)
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Float32, reflect.Float64:
+	case reflect.String:
+		_, e := strconv.ParseInt(v.(string), 10, 64)
+		isInt = e == nil
+	default:
+		isInt = true
+	}
+	return rv.Float(), isInt, nil
+}
+
+// compare compares l and r as numbers, or as strings when they aren't numbers.
+func compare(op string, l, r interface{}) bool {
+	var c int
+	lf, _, le := toNumber(l)
+	rf, _, re := toNumber(r)
+	if le == nil && re == nil {
+		switch {
+		case lf < rf:
+			c = -1
+		case lf > rf:
+			c = 1
+		}
+	} else {
+		c = strings.Compare(toString(l), toString(r))
+	}
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+// truthy reports whether v is not nil, false, zero or blank string.
+func truthy(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	if f, ok := toFloat(v); ok {
+		return f != 0
+	}
+	return toString(v) != ""
+}
@@ -0,0 +1,79 @@
+package xlsxt
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpression(t *testing.T) {
+	cleanHelpers()
+	RegisterHelper("double", func(f float64) float64 { return f * 2 })
+	RegisterHelper("upper", strings.ToUpper)
+	in := map[string]interface{}{
+		"price":      1.5,
+		"qty":        2,
+		"total":      10,
+		"discount":   "3",
+		"score":      60,
+		"name":       "bob",
+		"items":      []map[string]interface{}{{"price": 1}, {"price": 2}},
+		"first-name": "Bob",
+		"a/b":        "x",
+	}
+	tests := []struct {
+		name     string
+		args     string
+		want     interface{}
+		wantErr  bool
+		parseErr bool
+	}{
+		{name: "multiply float", args: "{{price * qty}}", want: float64(3)},
+		{name: "minus int", args: "{{total - discount}}", want: int64(7)},
+		{name: "compare", args: "{{score >= 60}}", want: true},
+		{name: "precedence", args: "{{total - discount * 2}}", want: int64(4)},
+		{name: "parentheses", args: "{{(total - discount) * 2 + 1}}", want: int64(15)},
+		{name: "nested parentheses", args: "{{((total - 1) / (qty + 1))}}", want: float64(3)},
+		{name: "divide", args: "{{total / 4}}", want: 2.5},
+		{name: "mod", args: "{{total % 3}}", want: int64(1)},
+		{name: "unary minus", args: "{{- total + 1}}", want: int64(-9)},
+		{name: "compare string", args: `{{name == "bob" && score > 50}}`, want: true},
+		{name: "or short circuit", args: `{{score < 50 || name != "bob"}}`, want: false},
+		{name: "nil key", args: "{{missing + 1}}", want: int64(1)},
+		{name: "helper in expression", args: `{{sum items "price" * 2}}`, want: float64(6)},
+		{name: "pipeline after expression", args: `{{price * qty | double}}`, want: float64(6)},
+		{name: "without spaces", args: "{{price*qty}}", want: float64(3)},
+		{name: "without spaces compare", args: "{{score>=60&&total!=1}}", want: true},
+		{name: "minus key", args: "{{total * -qty}}", want: int64(-20)},
+		{name: "negative number", args: "{{total * -2}}", want: int64(-20)},
+		{name: "parentheses without spaces", args: "{{(total-1)/(qty+1)}}", want: float64(3)},
+		{name: "operator in bracket", args: "{{items[-1].price+1}}", want: int64(3)},
+		{name: "key with minus", args: "{{first-name}}", want: "Bob"},
+		{name: "key with divide", args: "{{a/b}}", want: "x"},
+		{name: "helper with key with minus", args: "{{upper first-name}}", want: "BOB"},
+		{name: "key with minus in expression", args: `{{first-name == "Bob"}}`, want: true},
+		{name: "divide by zero", args: "{{total / 0}}", wantErr: true},
+		{name: "not a number", args: "{{name * 2}}", wantErr: true},
+		{name: "without operand", args: "{{total +}}", parseErr: true},
+		{name: "without close paren", args: "{{(total + 1}}", parseErr: true},
+		{name: "without operator", args: "{{total (1 + 1)}}", parseErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewParse(tt.args)
+			if (err != nil) != tt.parseErr {
+				t.Fatalf("NewParse() error = %v, wantErr %v", err, tt.parseErr)
+			}
+			if err != nil {
+				return
+			}
+			got, err := p.Exec(nil, in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse.Exec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse.Exec() = (%T)%v, want (%T)%v", got, got, tt.want, tt.want)
+			}
+		})
+	}
+}
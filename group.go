@@ -0,0 +1,138 @@
+package xlsxt
+
+import (
+	"reflect"
+
+	"github.com/360EntSecGroup-Skylar/excelize/v2"
+)
+
+// renderGroup renders `{{group key by field}}` block once per group, the group
+// data has the field value, `items`, `count` and the aggregates `sum`, `avg`,
+// `min`, `max` of number fields, like `{{sum.salary}}`.
+// With `outline`, the rows of group except the first one (usually header) and
+// the last one (usually subtotal) are grouped in outline, so the group could be
+// collapsed to its header and subtotal.
+func (m *Xlsxt) renderGroup(write *excelize.StreamWriter, key, field string, outline bool, rowsData [][]string, tplOffset, offset int) (renderLine int, err error) {
+	parentData := m.curSheetData
+	defer func() { m.curSheetData = parentData }()
+	scope := excludeKeyMap(parentData, key)
+	if outline {
+		m.curSheet.depth++
+		defer func() { m.curSheet.depth-- }()
+	}
+
+	v, err := lookupPath(parentData, key)
+	if err != nil {
+		return 0, err
+	}
+	groups, err := groupBy(v, field)
+	if err != nil {
+		return 0, err
+	}
+	for i, g := range groups {
+		if m.ctx.Err() != nil {
+			return 0, RenderCancel
+		}
+		meta := loopMeta(i, i == len(groups)-1)
+		meta[countKey] = len(groups)
+		m.curSheetData = m.itemScope(scope, parentData, "", g, meta)
+		l, err := m.renderRows(write, rowsData, tplOffset, offset)
+		if err != nil {
+			return 0, err
+		}
+		if outline {
+			for r := offset + 2; r < offset+l; r++ {
+				m.curSheet.setOutline(r)
+			}
+		}
+		renderLine += l
+		offset += l
+	}
+	return
+}
+
+// groupBy partitions the items of collection v by the value of field,
+// the groups are in order of first appearance.
+func groupBy(v interface{}, field string) ([]map[string]interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	iter, err := newRangeIter(v)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		groups []map[string]interface{}
+		index  = make(map[string]int)
+	)
+	for {
+		item, ok, err := iter()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		k := toString(item[field])
+		i, in := index[k]
+		if !in {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, map[string]interface{}{field: item[field]})
+		}
+		items, _ := groups[i]["items"].([]map[string]interface{})
+		groups[i]["items"] = append(items, item)
+	}
+	for _, g := range groups {
+		items := g["items"].([]map[string]interface{})
+		g["count"] = len(items)
+		g["sum"], g["avg"], g["min"], g["max"] = aggregate(items)
+	}
+	return groups, nil
+}
+
+// aggregate returns the sum, average, min and max of number fields in items.
+func aggregate(items []map[string]interface{}) (sums, avgs, mins, maxs map[string]interface{}) {
+	sums, avgs = make(map[string]interface{}), make(map[string]interface{})
+	mins, maxs = make(map[string]interface{}), make(map[string]interface{})
+	counts := make(map[string]int)
+	for _, item := range items {
+		for k, v := range item {
+			f, ok := toFloat(v)
+			if !ok {
+				continue
+			}
+			counts[k]++
+			if counts[k] == 1 {
+				sums[k], mins[k], maxs[k] = f, f, f
+				continue
+			}
+			sums[k] = sums[k].(float64) + f
+			if f < mins[k].(float64) {
+				mins[k] = f
+			}
+			if f > maxs[k].(float64) {
+				maxs[k] = f
+			}
+		}
+	}
+	for k, n := range counts {
+		avgs[k] = sums[k].(float64) / float64(n)
+	}
+	return
+}
+
+// toFloat converts number v to float64.
+func toFloat(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
@@ -6,14 +6,17 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var (
 	typeOfError   = reflect.TypeOf((*error)(nil)).Elem()
 	typeOfContext = reflect.TypeOf((*context.Context)(nil)).Elem()
 	typeOfString  = reflect.TypeOf("")
+	typeOfTime    = reflect.TypeOf(time.Time{})
 	funcNoStart   = errors.New("function without start")
 	funcNoKey     = errors.New("function without valid key")
 	funcNoEnd     = errors.New("function without end")
@@ -27,6 +30,24 @@ type helper struct {
 	in    []reflect.Type
 	outV  reflect.Type
 	outE  bool
+	// the last in is variadic like `parts ...string`
+	variadic bool
+}
+
+// inType returns the type of i-th param, params of variadic are the element type.
+func (h *helper) inType(i int) reflect.Type {
+	if h.variadic && i >= len(h.in)-1 {
+		return h.in[len(h.in)-1].Elem()
+	}
+	return h.in[i]
+}
+
+// checkParamCount checks the count of params, variadic could be empty.
+func (h *helper) checkParamCount(n int) bool {
+	if h.variadic {
+		return n >= len(h.in)-1
+	}
+	return n == len(h.in)
 }
 
 func RegisterHelper(key string, f interface{}) error {
@@ -53,7 +74,7 @@ func new(v reflect.Value) (*helper, error) {
 	if rn > 2 {
 		return nil, errors.New("Return value size > 2.")
 	}
-	h := &helper{f: v}
+	h := &helper{f: v, variadic: v.Type().IsVariadic()}
 	if rn == 1 {
 		if v.Type().Out(0).Implements(typeOfError) {
 			h.outE = true
@@ -70,7 +91,7 @@ func new(v reflect.Value) (*helper, error) {
 
 	// check out type
 	if h.outV != nil {
-		if !isSupportType(h.outV) {
+		if !isSupportType(h.outV) && h.outV != typeOfCellValue {
 			return nil, fmt.Errorf("Return value not base type: %v", h.outV)
 		}
 	}
@@ -84,9 +105,13 @@ func new(v reflect.Value) (*helper, error) {
 	h.in = make([]reflect.Type, 0, ini-i)
 	for ; i < ini; i++ {
 		inv := v.Type().In(i)
+		ck := inv
+		if h.variadic && i == ini-1 {
+			ck = inv.Elem()
+		}
 
 		// check in type
-		if !isSupportType(inv) {
+		if !isSupportType(ck) && ck != typeOfCellValue {
 			return nil, fmt.Errorf("In value not base type: index:%d, %v", i, inv)
 		}
 
@@ -106,6 +131,7 @@ const (
 	general parmType = iota
 	key
 	function
+	expression
 )
 
 type parm struct {
@@ -118,20 +144,11 @@ func (p *parm) exec(ctx context.Context, in map[string]interface{}) (result inte
 	case general:
 		return p.v, nil
 	case key:
-		if strings.Contains(p.v.(string), ".") {
-			result = in
-			for _, key := range strings.Split(p.v.(string), ".") {
-				if in, err = toStringKeyMap(result); err != nil || in == nil {
-					return
-				}
-				result = in[key]
-			}
-			return
-		}
-
-		return in[p.v.(string)], nil
+		return lookupPath(in, p.v.(string))
 	case function:
 		return p.v.(*Parse).Exec(ctx, in)
+	case expression:
+		return p.v.(*operation).exec(ctx, in)
 	}
 	return nil, errors.New("Not support parm type.")
 }
@@ -148,55 +165,102 @@ func (p *Parse) Exec(ctx context.Context, in map[string]interface{}) (rv interfa
 		}
 	}()
 
+	// the result of single expression keeps its type
+	if p.f == nil && len(p.ps) == 1 && p.ps[0].t == expression {
+		return p.ps[0].exec(ctx, in)
+	}
+
 	// if p.f = nil, will concat eval parm
 	if p.f == nil {
-		var sb strings.Builder
+		var (
+			sb strings.Builder
+			cv *cellValue
+		)
 		for _, item := range p.ps {
-			if iv, err := item.exec(ctx, in); err != nil {
+			iv, err := item.exec(ctx, in)
+			if err != nil {
 				return nil, err
-			} else if ivs, e := interface2AppointType(iv, typeOfString); e != nil {
+			}
+			iv, cv = unwrapCellValue(iv, cv)
+			if ivs, e := interface2AppointType(iv, typeOfString); e != nil {
 				return nil, e
 			} else {
 				sb.WriteString(ivs.String())
 			}
 		}
+		if cv != nil {
+			cv.value = sb.String()
+			return cv, nil
+		}
 		return sb.String(), nil
 	}
 
-	vs := make([]reflect.Value, 0, len(p.f.in)+1)
+	vs := make([]reflect.Value, 0, len(p.ps)+1)
 	if p.f.ctxIn {
 		vs = append(vs, reflect.ValueOf(ctx))
 	}
 
+	// cell attributes of params will be kept in result
+	var cv *cellValue
 	for i, op := range p.ps {
 		var ev interface{}
 		if ev, err = op.exec(ctx, in); err != nil {
 			return nil, err
 		}
+		it := p.f.inType(i)
+		if it != typeOfCellValue {
+			ev, cv = unwrapCellValue(ev, cv)
+		} else if _, ok := ev.(*cellValue); !ok {
+			ev = &cellValue{value: ev}
+		}
 		var vv reflect.Value
-		if vv, err = interface2AppointType(ev, p.f.in[i]); err != nil {
+		if vv, err = interface2AppointType(ev, it); err != nil {
 			return
 		}
 		vs = append(vs, vv)
 	}
 
+	defer func() {
+		if cv != nil && err == nil {
+			rv = wrapCellValue(rv, cv)
+		}
+	}()
+
 	r := p.f.f.Call(vs)
 	switch len(r) {
 	case 0:
 		return nil, nil
 	case 1:
 		if p.f.outE {
-			return nil, r[0].Interface().(error)
+			e, _ := r[0].Interface().(error)
+			return nil, e
 		} else {
 			return r[0].Interface(), nil
 		}
 	case 2:
-		return r[0].Interface(), r[1].Interface().(error)
+		e, _ := r[1].Interface().(error)
+		return r[0].Interface(), e
 	default:
 		return nil, errors.New("Invalid return value.")
 	}
 }
 
+// value returns the raw value of p, the single key or literal keeps its type,
+// which is converted to string by Exec.
+func (p *Parse) value(ctx context.Context, in map[string]interface{}) (v interface{}, err error) {
+	if p != nil && p.f == nil && len(p.ps) == 1 {
+		sp := p.ps[0]
+		if sp.t == key {
+			sp.t, sp.v = literal(sp.v.(string))
+		}
+		v, err = sp.exec(ctx, in)
+	} else {
+		v, err = p.Exec(ctx, in)
+	}
+	v, _ = unwrapCellValue(v, nil)
+	return
+}
+
 func interface2AppointType(i interface{}, t reflect.Type) (result reflect.Value, err error) {
 	// set default value when i = nil.
 	if i == nil {
@@ -213,6 +277,8 @@ func interface2AppointType(i interface{}, t reflect.Type) (result reflect.Value,
 			i = ""
 		case reflect.Map:
 			i = reflect.MakeMap(t).Interface()
+		case reflect.Slice, reflect.Array, reflect.Struct, reflect.Ptr, reflect.Interface:
+			return reflect.Zero(t), nil
 		}
 	}
 
@@ -220,6 +286,36 @@ func interface2AppointType(i interface{}, t reflect.Type) (result reflect.Value,
 	if v.Type() == t {
 		return v, nil
 	}
+	switch t.Kind() {
+	case reflect.Interface:
+		if !v.Type().Implements(t) {
+			return v, fmt.Errorf("%v couldn't convert to %v.", i, t)
+		}
+		return v.Convert(t), nil
+	case reflect.Ptr:
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return reflect.Zero(t), nil
+		}
+		if result, err = interface2AppointType(i, t.Elem()); err != nil {
+			return
+		}
+		p := reflect.New(t.Elem())
+		p.Elem().Set(result)
+		return p, nil
+	}
+	// pointer to value
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return interface2AppointType(nil, t)
+		}
+		return interface2AppointType(v.Elem().Interface(), t)
+	}
+	if tm, ok := i.(time.Time); ok && t.Kind() == reflect.String {
+		return reflect.ValueOf(tm.Format(time.RFC3339)), nil
+	}
+	if s, ok := i.(string); ok && t == typeOfTime {
+		return parseTime(s)
+	}
 	if t.Kind() == reflect.String {
 		bs, err := json.Marshal(i)
 		if err != nil {
@@ -227,7 +323,8 @@ func interface2AppointType(i interface{}, t reflect.Type) (result reflect.Value,
 		}
 		return reflect.ValueOf(string(bs)), nil
 	}
-	if v.Kind() == reflect.Map {
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
 		bs, err := json.Marshal(i)
 		if err != nil {
 			return v, fmt.Errorf("%v couldn't marshal.", i)
@@ -340,10 +437,33 @@ func interface2AppointType(i interface{}, t reflect.Type) (result reflect.Value,
 			return v, fmt.Errorf("%v couldn't convert to map.", i)
 		}
 		err = json.Unmarshal([]byte(s), result.Addr().Interface())
+	case reflect.Slice, reflect.Array, reflect.Struct:
+		if !sb {
+			return v, fmt.Errorf("%v couldn't convert to %v.", i, t)
+		}
+		if e := json.Unmarshal([]byte(s), result.Addr().Interface()); e != nil {
+			return v, fmt.Errorf("%s couldn't convert to %v: %v", s, t, e)
+		}
 	}
 	return
 }
 
+// timeLayouts are the layouts of string could convert to time.Time.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+func parseTime(s string) (reflect.Value, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return reflect.ValueOf(t), nil
+		}
+	}
+	return reflect.ValueOf(s), fmt.Errorf("%s couldn't convert to time.", s)
+}
+
 func isSupportType(t reflect.Type) bool {
 	switch t.Kind() {
 	case reflect.Bool,
@@ -351,7 +471,8 @@ func isSupportType(t reflect.Type) bool {
 		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
 		reflect.Float32, reflect.Float64,
 		reflect.String,
-		reflect.Map:
+		reflect.Map,
+		reflect.Slice, reflect.Array, reflect.Struct, reflect.Ptr, reflect.Interface:
 		return true
 	default:
 		return false
@@ -394,6 +515,9 @@ type walkParse struct {
 	pcur        int
 	stack       []struct{}
 	inQuote     bool
+	// bracket depth and quote in key path like `m["a b"]`
+	keyBracket int
+	keyQuote   bool
 }
 
 // must check in each step.
@@ -427,7 +551,14 @@ func (wp *walkParse) nextParm() (*parm, error) {
 // or in func ` `
 // or in func and inQuote
 func (wp *walkParse) nextSection() (v string) {
+	wp.keyBracket, wp.keyQuote = 0, false
 	for !wp.isToken() {
+		// skip the escaped char in quote
+		if (wp.inQuote || wp.keyQuote) && wp.pEqual('\\') && wp.pcur+1 < wp.v_max_index {
+			wp.pcur++
+		} else if !wp.inQuote && len(wp.stack) > 0 {
+			wp.walkKey()
+		}
 		wp.pcur++
 	}
 	if wp.cur == wp.pcur {
@@ -468,10 +599,46 @@ func (wp *walkParse) dealFuncParam() (*parm, error) {
 			wp.cur = wp.pcur
 		}()
 	}
-	p.v = wp.nextSection()
+	if p.t == general {
+		p.v = unescapeQuote(wp.nextSection())
+		return p, nil
+	}
+	p.t, p.v = literal(wp.nextSection())
 	return p, nil
 }
 
+var (
+	numberRgx       = regexp.MustCompile(`^[-+]?[0-9]+(\.[0-9]+)?$`)
+	unquoteReplacer = strings.NewReplacer(`\\`, `\`, `\"`, `"`)
+)
+
+// literal returns the int, float, bool and nil value of literal s,
+// others are keys.
+func literal(s string) (parmType, interface{}) {
+	switch s {
+	case "true":
+		return general, true
+	case "false":
+		return general, false
+	case "nil":
+		return general, nil
+	}
+	if numberRgx.MatchString(s) {
+		if i, err := strconv.Atoi(s); err == nil {
+			return general, i
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return general, f
+		}
+	}
+	return key, s
+}
+
+// unescapeQuote unescapes `\"` and `\\` in quoted string.
+func unescapeQuote(s string) string {
+	return unquoteReplacer.Replace(s)
+}
+
 func (wp *walkParse) dealFunc() (p *parm, err error) {
 	wp.stack = append(wp.stack, struct{}{})
 	defer wp.endFunc()
@@ -480,41 +647,109 @@ func (wp *walkParse) dealFunc() (p *parm, err error) {
 	wp.pcur += 2
 	wp.cur = wp.pcur
 
-	k := wp.nextSection()
-	if k == "" {
+	var (
+		k  string
+		hp = parm{t: key}
+	)
+	if wp.pEqual('"') {
+		// quoted string head like `{{"text"}}` or `{{"text" | upper}}`
+		var qp *parm
+		if qp, err = wp.dealFuncParam(); err != nil {
+			return
+		}
+		hp = *qp
+	} else if k = wp.nextSection(); k == "" {
 		return nil, funcNoKey
+	} else {
+		hp.t, hp.v = literal(k)
 	}
 
 	// only key no any param will return key, not func.
-	if wp.pEqual('}') {
+	head := splitOperators([]parm{hp})
+	if wp.pEqual('}') && len(head) == 1 {
+		if hp.t == general && k == "" {
+			return &hp, nil
+		}
+		if head[0].t == expression {
+			return &head[0], nil
+		}
 		return &parm{t: key, v: k}, nil
 	}
 
-	// check have regist func
-	f, in := helperMap[k]
-	if !in {
-		return nil, fmt.Errorf("Not func `%s`.", k)
-	}
-
-	parse := &Parse{f: f}
+	var ps []parm
 	// check is end first
 	for !wp.isPEnd() && !wp.pEqual('}') {
 		if p, err = wp.dealFuncParam(); err != nil {
 			return
 		}
 		if p != nil {
-			parse.ps = append(parse.ps, *p)
+			ps = append(ps, *p)
 		}
 	}
 
-	if len(parse.ps) != len(f.in) {
-		err = fmt.Errorf("Helper(%s) need %d param, now have %d.", k, len(f.in), len(parse.ps))
-	} else {
-		p = &parm{t: function, v: parse}
+	stages := splitPipeline(splitOperators(ps))
+	// the head of pipeline could be a key or literal, like `{{name | upper}}`
+	f, in := getHelper(k)
+	if head = append(head[:len(head):len(head)], stages[0]...); hasOperator(head) {
+		if p, err = newExpression(head); err != nil {
+			return nil, err
+		}
+	} else if len(stages) > 1 && len(stages[0]) == 0 && (!in || !f.checkParamCount(0)) {
+		p = &hp
+	} else if p, err = newFuncParm(k, stages[0], nil); err != nil {
+		return nil, err
+	}
+
+	// the value flows into the last param of next helper
+	for _, stage := range stages[1:] {
+		if len(stage) == 0 || stage[0].t != key {
+			return nil, errors.New("pipeline without helper")
+		}
+		if p, err = newFuncParm(stage[0].v.(string), stage[1:], p); err != nil {
+			return nil, err
+		}
 	}
 	return
 }
 
+// pipe is the separator of pipeline.
+const pipe = "|"
+
+// splitPipeline splits params by `|`, the first stage is the params of head.
+func splitPipeline(ps []parm) [][]parm {
+	stages := [][]parm{nil}
+	for _, p := range ps {
+		if p.t == key && p.v == pipe {
+			stages = append(stages, nil)
+			continue
+		}
+		stages[len(stages)-1] = append(stages[len(stages)-1], p)
+	}
+	return stages
+}
+
+// newFuncParm returns the function param of helper k,
+// last is appended to the params when it isn't nil.
+func newFuncParm(k string, ps []parm, last *parm) (*parm, error) {
+	// check have regist func
+	f, in := getHelper(k)
+	if !in {
+		return nil, fmt.Errorf("Not func `%s`.", k)
+	}
+
+	parse := &Parse{f: f, ps: ps}
+	if last != nil {
+		parse.ps = append(parse.ps[:len(ps):len(ps)], *last)
+	}
+	if !f.checkParamCount(len(parse.ps)) {
+		if f.variadic {
+			return nil, fmt.Errorf("Helper(%s) need at least %d param, now have %d.", k, len(f.in)-1, len(parse.ps))
+		}
+		return nil, fmt.Errorf("Helper(%s) need %d param, now have %d.", k, len(f.in), len(parse.ps))
+	}
+	return &parm{t: function, v: parse}, nil
+}
+
 func (wp *walkParse) endFunc() {
 	if wp.isEnd() {
 		return
@@ -524,15 +759,34 @@ func (wp *walkParse) endFunc() {
 	wp.cur = wp.pcur
 }
 
+// walkKey records the brackets and quotes in key path,
+// the spaces in them don't end the key.
+func (wp *walkParse) walkKey() {
+	switch c := wp.v[wp.pcur]; {
+	case wp.keyQuote:
+		wp.keyQuote = c != '"'
+	case c == '[':
+		wp.keyBracket++
+	case c == ']' && wp.keyBracket > 0:
+		wp.keyBracket--
+	case c == '"' && wp.keyBracket > 0:
+		wp.keyQuote = true
+	}
+}
+
 func (wp *walkParse) isToken() bool {
 	if wp.isPEnd() {
 		return true
 	}
+	if wp.keyQuote {
+		return false
+	}
 	switch wp.v[wp.pcur] {
 	case ' ':
-		return !wp.inQuote && len(wp.stack) > 0
+		return !wp.inQuote && len(wp.stack) > 0 && wp.keyBracket == 0
 	case '"':
-		return len(wp.stack) > 0
+		// quote in key like `map["a"]` is a part of key
+		return len(wp.stack) > 0 && (wp.inQuote || wp.cur == wp.pcur)
 	case '{', '}':
 		return !wp.inQuote && wp.nextPEqual(wp.v[wp.pcur])
 	default:
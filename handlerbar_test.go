@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func cleanHelpers() {
@@ -568,6 +570,109 @@ func Test_interface2AppointType(t *testing.T) {
 			},
 			wantResult: reflect.ValueOf(map[string]string{"key": "123"}),
 		},
+		{
+			name: "nil2[]int",
+			args: args{
+				t: reflect.TypeOf([]int{}),
+			},
+			wantResult: reflect.ValueOf([]int(nil)),
+		},
+		{
+			name: "[]interface{}2[]int",
+			args: args{
+				i: []interface{}{1, 2.0},
+				t: reflect.TypeOf([]int{}),
+			},
+			wantResult: reflect.ValueOf([]int{1, 2}),
+		},
+		{
+			name: "json_string2[2]string",
+			args: args{
+				i: `["a","b"]`,
+				t: reflect.TypeOf([2]string{}),
+			},
+			wantResult: reflect.ValueOf([2]string{"a", "b"}),
+		},
+		{
+			name: "not_match_type_slice2[]int",
+			args: args{
+				i: []string{"a"},
+				t: reflect.TypeOf([]int{}),
+			},
+			wantErr: true,
+		},
+		{
+			name: "int2[]int",
+			args: args{
+				i: 1,
+				t: reflect.TypeOf([]int{}),
+			},
+			wantErr: true,
+		},
+		{
+			name: "map2struct",
+			args: args{
+				i: map[string]interface{}{"Name": "a", "Age": 1},
+				t: reflect.TypeOf(struct{ Name string }{}),
+			},
+			wantResult: reflect.ValueOf(struct{ Name string }{"a"}),
+		},
+		{
+			name: "ptr2struct",
+			args: args{
+				i: &struct{ Name string }{"a"},
+				t: reflect.TypeOf(struct{ Name string }{}),
+			},
+			wantResult: reflect.ValueOf(struct{ Name string }{"a"}),
+		},
+		{
+			name: "string2*int",
+			args: args{
+				i: "1",
+				t: reflect.TypeOf(intPtr(0)),
+			},
+			wantResult: reflect.ValueOf(intPtr(1)),
+		},
+		{
+			name: "int2interface{}",
+			args: args{
+				i: 1,
+				t: reflect.TypeOf((*interface{})(nil)).Elem(),
+			},
+			wantResult: reflect.ValueOf(1),
+		},
+		{
+			name: "int2error",
+			args: args{
+				i: 1,
+				t: typeOfError,
+			},
+			wantErr: true,
+		},
+		{
+			name: "string2time",
+			args: args{
+				i: "2020-01-02",
+				t: typeOfTime,
+			},
+			wantResult: reflect.ValueOf(time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)),
+		},
+		{
+			name: "not_time_string2time",
+			args: args{
+				i: "2020/01/02",
+				t: typeOfTime,
+			},
+			wantErr: true,
+		},
+		{
+			name: "time2string",
+			args: args{
+				i: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+				t: reflect.TypeOf(""),
+			},
+			wantResult: reflect.ValueOf("2020-01-02T03:04:05Z"),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -680,20 +785,37 @@ func Test_isSupportType(t *testing.T) {
 			want: true,
 		},
 		{
-			name: "slice not!!!",
+			name: "slice",
 			args: make([]int, 0),
+			want: true,
 		},
 		{
-			name: "array not!!!",
+			name: "array",
 			args: [0]int{},
+			want: true,
 		},
 		{
-			name: "struct not!!!",
+			name: "struct",
 			args: args{},
+			want: true,
 		},
 		{
-			name: "ptr not!!!",
+			name: "time",
+			args: time.Time{},
+			want: true,
+		},
+		{
+			name: "ptr",
 			args: &i,
+			want: true,
+		},
+		{
+			name: "chan not!!!",
+			args: make(chan int),
+		},
+		{
+			name: "func not!!!",
+			args: func() {},
 		},
 	}
 	for _, tt := range tests {
@@ -841,6 +963,55 @@ func TestParse_Exec(t *testing.T) {
 			},
 			wantRv: "string10false",
 		},
+		{
+			name: "slice in retun int",
+			fields: fields{
+				f:  wrapHelper(func(items []struct{ Price int }) int { return len(items) }),
+				ps: []parm{{t: key, v: "items"}},
+			},
+			args: args{
+				in: map[string]interface{}{"items": []map[string]interface{}{{"Price": 1}, {"Price": 2}}},
+			},
+			wantRv: 2,
+		},
+		{
+			name: "slice in with mismatch",
+			fields: fields{
+				f:  wrapHelper(func(items []int) int { return len(items) }),
+				ps: []parm{{t: key, v: "items"}},
+			},
+			args: args{
+				in: map[string]interface{}{"items": "a,b"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "variadic in without variadic param",
+			fields: fields{
+				f:  wrapHelper(func(sep string, parts ...string) string { return strings.Join(parts, sep) }),
+				ps: []parm{{t: general, v: "-"}},
+			},
+			wantRv: "",
+		},
+		{
+			name: "variadic in with variadic params",
+			fields: fields{
+				f:  wrapHelper(func(sep string, parts ...string) string { return strings.Join(parts, sep) }),
+				ps: []parm{{t: general, v: "-"}, {t: general, v: "a"}, {t: key, v: "key"}, {t: general, v: 1}},
+			},
+			args: args{
+				in: map[string]interface{}{"key": "b"},
+			},
+			wantRv: "a-b-1",
+		},
+		{
+			name: "time in retun time",
+			fields: fields{
+				f:  wrapHelper(func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }),
+				ps: []parm{{t: general, v: "2020-01-02"}},
+			},
+			wantRv: time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1104,6 +1275,100 @@ func TestNewParse(t *testing.T) {
 			help:    map[string]interface{}{"exist": func(s, s1 string) {}},
 			wantErr: true,
 		},
+		{
+			name: "function with number literal",
+			args: "{{exist price 2}}",
+			help: map[string]interface{}{"exist": func(f float64, i int) {}},
+			want: &Parse{f: wrapHelper(func(f float64, i int) {}), ps: []parm{{t: key, v: "price"}, {t: general, v: 2}}},
+		},
+		{
+			name: "function with literals",
+			args: "{{exist -1 1.5 true false nil 1e5 \"2\"}}",
+			help: map[string]interface{}{"exist": func(ss ...string) {}},
+			want: &Parse{f: wrapHelper(func(ss ...string) {}), ps: []parm{
+				{t: general, v: -1}, {t: general, v: 1.5}, {t: general, v: true},
+				{t: general, v: false}, {t: general, v: nil}, {t: key, v: "1e5"}, {t: general, v: "2"},
+			}},
+		},
+		{
+			name: "function with escaped quote",
+			args: `{{exist "say \"hi\" \\" key}}`,
+			help: map[string]interface{}{"exist": func(s, s1 string) {}},
+			want: &Parse{f: wrapHelper(func(s, s1 string) {}), ps: []parm{{t: general, v: `say "hi" \`}, {t: key, v: "key"}}},
+		},
+		{
+			name: "pipeline with key head",
+			args: "{{name | exist}}",
+			help: map[string]interface{}{"exist": func(s string) string { return s }},
+			want: &Parse{f: wrapHelper(func(s string) string { return s }), ps: []parm{{t: key, v: "name"}}},
+		},
+		{
+			name: "pipeline with params",
+			args: `{{exist "a" name | exist "b"}}`,
+			help: map[string]interface{}{"exist": func(s, s1 string) string { return s }},
+			want: &Parse{f: wrapHelper(func(s, s1 string) string { return s }), ps: []parm{
+				{t: general, v: "b"},
+				{t: function, v: &Parse{f: wrapHelper(func(s, s1 string) string { return s }), ps: []parm{{t: general, v: "a"}, {t: key, v: "name"}}}},
+			}},
+		},
+		{
+			name:    "pipeline with not exist helper",
+			args:    "{{name | notExist}}",
+			help:    map[string]interface{}{"exist": func(s string) string { return s }},
+			wantErr: true,
+		},
+		{
+			name:    "pipeline without helper",
+			args:    "{{name | }}",
+			help:    map[string]interface{}{"exist": func(s string) string { return s }},
+			wantErr: true,
+		},
+		{
+			name:    "pipeline with param size not equal in",
+			args:    "{{name | exist}}",
+			help:    map[string]interface{}{"exist": func(s, s1 string) string { return s }},
+			wantErr: true,
+		},
+		{
+			name: "function with quoted key in bracket",
+			args: `{{exist map["a"] "b"}}`,
+			help: map[string]interface{}{"exist": func(s, s1 string) {}},
+			want: &Parse{f: wrapHelper(func(s, s1 string) {}), ps: []parm{{t: key, v: `map["a"]`}, {t: general, v: "b"}}},
+		},
+		{
+			name: "quoted string only",
+			args: `{{"a b"}}`,
+			want: &Parse{ps: []parm{{t: general, v: "a b"}}},
+		},
+		{
+			name: "key with space in bracket",
+			args: `{{map["a b"]}}`,
+			want: &Parse{ps: []parm{{t: key, v: `map["a b"]`}}},
+		},
+		{
+			name: "function with quoted key with space and bracket",
+			args: `{{exist map["a ]\" b"] m[x[0]]}}`,
+			help: map[string]interface{}{"exist": func(s, s1 string) {}},
+			want: &Parse{f: wrapHelper(func(s, s1 string) {}), ps: []parm{{t: key, v: `map["a ]\" b"]`}, {t: key, v: "m[x[0]]"}}},
+		},
+		{
+			name: "variadic function without variadic param",
+			args: "{{exist key}}",
+			help: map[string]interface{}{"exist": func(s string, ss ...string) {}},
+			want: &Parse{f: wrapHelper(func(s string, ss ...string) {}), ps: []parm{{t: key, v: "key"}}},
+		},
+		{
+			name: "variadic function with variadic params",
+			args: `{{exist key "a" b}}`,
+			help: map[string]interface{}{"exist": func(s string, ss ...string) {}},
+			want: &Parse{f: wrapHelper(func(s string, ss ...string) {}), ps: []parm{{t: key, v: "key"}, {t: general, v: "a"}, {t: key, v: "b"}}},
+		},
+		{
+			name:    "variadic function param size less than in",
+			args:    "{{exist key}}",
+			help:    map[string]interface{}{"exist": func(s, s1 string, ss ...string) {}},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,144 @@
+package xlsxt
+
+import (
+	"bytes"
+
+	"github.com/360EntSecGroup-Skylar/excelize/v2"
+)
+
+// RegisterPartial registers the sheets of workbook content as partials,
+// which could be included by `{{include "SheetName"}}` when the template
+// hasn't the sheet.
+func (m *Xlsxt) RegisterPartial(content []byte) error {
+	f, err := excelize.OpenReader(bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	m.partials = append(m.partials, f)
+	m.partialSheets = make(map[string]*partialSheet)
+	return nil
+}
+
+// partialSheet is the sheet could be included, the styles are the style ids
+// of cells in file.
+type partialSheet struct {
+	name   string
+	file   *excelize.File
+	rows   [][]string
+	styles map[string]int
+	merges []excelize.MergeCell
+}
+
+// including is the partial being included,
+// rows records the partial row is rendered to rows.
+type including struct {
+	*partialSheet
+	rows map[int][]int
+}
+
+// renderInclude renders the rows of partial name with current data, the styles
+// and merges of partial are copied, the variables defined in partial are
+// visible in it only.
+func (m *Xlsxt) renderInclude(write *excelize.StreamWriter, name string, offset int) (renderLine int, err error) {
+	for _, inc := range m.curSheet.includes {
+		if inc.name == name {
+			return 0, IncludeCycle
+		}
+	}
+	ps, err := m.partial(name)
+	if err != nil {
+		return 0, err
+	}
+	parentData := m.curSheetData
+	inc := &including{partialSheet: ps, rows: make(map[int][]int)}
+	m.curSheet.includes = append(m.curSheet.includes, inc)
+	defer func() {
+		m.curSheetData = parentData
+		m.curSheet.includes = m.curSheet.includes[:len(m.curSheet.includes)-1]
+	}()
+	if renderLine, err = m.renderRows(write, ps.rows, 0, offset); err != nil {
+		return 0, err
+	}
+	return renderLine, m.curSheet.addMerges(inc)
+}
+
+// partial returns the sheet name, the sheet in template is preferred to the
+// registered partials.
+func (m *Xlsxt) partial(name string) (*partialSheet, error) {
+	if ps, in := m.partialSheets[name]; in {
+		return ps, nil
+	}
+	for _, f := range append([]*excelize.File{m.file}, m.partials...) {
+		if f.GetSheetIndex(name) == -1 {
+			continue
+		}
+		ps, err := newPartialSheet(f, name)
+		if err != nil {
+			return nil, err
+		}
+		m.partialSheets[name] = ps
+		return ps, nil
+	}
+	return nil, NotFoundPartial
+}
+
+// newPartialSheet reads the rows, styles and merges of sheet name in f,
+// the rows are extended to the styled blank cells.
+func newPartialSheet(f *excelize.File, name string) (*partialSheet, error) {
+	rows, err := f.GetRows(name)
+	if err != nil {
+		return nil, err
+	}
+	ps := &partialSheet{name: name, file: f, rows: rows, styles: make(map[string]int)}
+	if ps.merges, err = f.GetMergeCells(name); err != nil {
+		return nil, err
+	}
+	if err = loadWorksheet(f, name); err != nil {
+		return nil, err
+	}
+	ws := f.Sheet[worksheetPath(f, name)]
+	if ws == nil {
+		return ps, nil
+	}
+	for _, r := range ws.SheetData.Row {
+		for _, c := range r.C {
+			if c.S == 0 {
+				continue
+			}
+			col, row, err := excelize.CellNameToCoordinates(c.R)
+			if err != nil {
+				return nil, err
+			}
+			ps.styles[c.R] = c.S
+			for len(ps.rows) < row {
+				ps.rows = append(ps.rows, nil)
+			}
+			for len(ps.rows[row-1]) < col {
+				ps.rows[row-1] = append(ps.rows[row-1], "")
+			}
+		}
+	}
+	return ps, nil
+}
+
+// hiddenPartials returns the hidden sheets of template which are included,
+// they are excluded from result.
+func (m *Xlsxt) hiddenPartials() (map[string]bool, error) {
+	result := make(map[string]bool)
+	for _, sn := range m.file.GetSheetList() {
+		rows, err := m.file.GetRows(sn)
+		if err != nil {
+			return nil, err
+		}
+		for _, cells := range rows {
+			if len(cells) == 0 {
+				continue
+			}
+			ms := includeRgx.FindStringSubmatch(cells[0])
+			if len(ms) == 2 && m.file.GetSheetIndex(ms[1]) != -1 && !m.file.GetSheetVisible(ms[1]) {
+				result[ms[1]] = true
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,149 @@
+package xlsxt
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// lookupPath returns the value of key path in data. The path is separated by
+// `.`, slices and arrays are indexed by number like `items.0.name` or
+// `items[-1]`, and the key in bracket could be a quoted string like
+// `map["a b"]`, or another key path like `map[$key]`.
+// Missing key and index out of range return nil.
+func lookupPath(in map[string]interface{}, path string) (interface{}, error) {
+	if path == dotKey || !strings.ContainsAny(path, ".[") {
+		return in[path], nil
+	}
+	segs, err := splitPath(path)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{} = in
+	for _, seg := range segs {
+		k := seg.key
+		if seg.bracket {
+			switch {
+			case strings.HasPrefix(k, `"`) && strings.HasSuffix(k, `"`) && len(k) > 1:
+				k = unescapeQuote(k[1 : len(k)-1])
+			case numberRgx.MatchString(k):
+			default:
+				// dynamic key
+				var v interface{}
+				if v, err = lookupPath(in, k); err != nil {
+					return nil, err
+				}
+				k = toString(v)
+			}
+		}
+		if result = pathStep(result, k); result == nil {
+			return nil, nil
+		}
+	}
+	return result, nil
+}
+
+type pathSeg struct {
+	key     string
+	bracket bool
+}
+
+// splitPath splits `a.b[0][c.d]` to `a`, `b`, `[0]` and `[c.d]`.
+func splitPath(path string) (segs []pathSeg, err error) {
+	var sb strings.Builder
+	flush := func() {
+		if sb.Len() > 0 {
+			segs = append(segs, pathSeg{key: sb.String()})
+			sb.Reset()
+		}
+	}
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '.':
+			flush()
+		case '[':
+			flush()
+			end := matchBracket(path, i)
+			if end == -1 {
+				return nil, errors.New("Key path without `]`.")
+			}
+			segs = append(segs, pathSeg{key: path[i+1 : end], bracket: true})
+			i = end
+		default:
+			sb.WriteByte(path[i])
+		}
+	}
+	flush()
+	return
+}
+
+// matchBracket returns the index of `]` matches the `[` at begin.
+func matchBracket(path string, begin int) int {
+	var (
+		depth   int
+		inQuote bool
+	)
+	for i := begin; i < len(path); i++ {
+		switch path[i] {
+		case '\\':
+			if inQuote {
+				i++
+			}
+		case '"':
+			inQuote = !inQuote
+		case '[':
+			if !inQuote {
+				depth++
+			}
+		case ']':
+			if !inQuote {
+				if depth--; depth == 0 {
+					return i
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// pathStep returns the value of key k in v, k is an index when v is a slice or
+// an array, negative index counts from the end, and a field name when v is a
+// struct.
+func pathStep(v interface{}, k string) interface{} {
+	if v == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		i, err := strconv.Atoi(k)
+		if err != nil {
+			return nil
+		}
+		if i < 0 {
+			i += rv.Len()
+		}
+		if i < 0 || i >= rv.Len() {
+			return nil
+		}
+		return rv.Index(i).Interface()
+	case reflect.Map, reflect.Struct:
+		if rv.Type() == typeOfTime {
+			return nil
+		}
+		skm, err := toStringKeyMap(rv.Interface())
+		if err != nil {
+			return nil
+		}
+		return skm[k]
+	default:
+		return nil
+	}
+}
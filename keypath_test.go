@@ -0,0 +1,99 @@
+package xlsxt
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testAddr struct {
+	City string
+	Zip  int `json:"zip"`
+}
+
+type testCustomer struct {
+	Name string
+	Addr testAddr
+	Tags []string
+	Born time.Time
+}
+
+func Test_lookupPath(t *testing.T) {
+	c := testCustomer{Name: "bob", Addr: testAddr{City: "NY", Zip: 1}, Tags: []string{"a", "b"}}
+	in := map[string]interface{}{
+		"name":  "n",
+		"items": []map[string]interface{}{{"name": "a"}, {"name": "b"}, {"name": "c"}},
+		"arr":   [2]int{1, 2},
+		"ptr":   &[]string{"x"},
+		"map":   map[string]interface{}{"a b": 1, "k": 2, "0": "zero", "nested": map[string]int{"v": 3}},
+		"key":   "k",
+		"$key":  "a b",
+		"idx":   1,
+		"c":     c,
+		"pc":    &c,
+		"cs":    []testCustomer{c, {Addr: testAddr{Zip: 2}}},
+	}
+	tests := []struct {
+		name    string
+		path    string
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "key", path: "name", want: "n"},
+		{name: "dot index", path: "items.1.name", want: "b"},
+		{name: "bracket index", path: "items[0].name", want: "a"},
+		{name: "negative index", path: "items[-1].name", want: "c"},
+		{name: "index out of range", path: "items[3].name", want: nil},
+		{name: "array", path: "arr.1", want: 2},
+		{name: "pointer to slice", path: "ptr[0]", want: "x"},
+		{name: "quoted key", path: `map["a b"]`, want: 1},
+		{name: "dynamic key", path: "map[key]", want: 2},
+		{name: "variable key", path: "map[$key]", want: 1},
+		{name: "dynamic index", path: "items[idx].name", want: "b"},
+		{name: "number key of map", path: "map.0", want: "zero"},
+		{name: "nested", path: "map[key].x", want: nil},
+		{name: "typed map", path: `map["nested"].v`, want: 3},
+		{name: "index of string", path: "name.0", want: nil},
+		{name: "missing", path: "missing.a[0]", want: nil},
+		{name: "struct field", path: "c.Name", want: "bob"},
+		{name: "nested struct field", path: "c.Addr.City", want: "NY"},
+		{name: "json tag of struct field", path: "c.Addr.zip", want: 1},
+		{name: "slice of struct field", path: "c.Tags[1]", want: "b"},
+		{name: "pointer to struct", path: "pc.Addr.City", want: "NY"},
+		{name: "struct in slice", path: "cs[-1].Addr.Zip", want: 2},
+		{name: "field of time", path: "c.Born.Year", want: nil},
+		{name: "without close bracket", path: "items[0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := lookupPath(in, tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("lookupPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lookupPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for tpl, want := range map[string]string{
+		`{{map["a b"]}}`:          "1",
+		`{{map["a b"]}}-{{name}}`: "1-n",
+		`{{map[$key] + 1}}`:       "2",
+		`{{sum cs "Addr.Zip"}}`:   "3",
+	} {
+		t.Run(tpl, func(t *testing.T) {
+			p, err := NewParse(tpl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := p.Exec(nil, in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if toString(got) != want {
+				t.Errorf("Parse.Exec() = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package xlsxt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var rangeKeywords = []string{"where", "sortBy", "limit"}
+
+// rangeClause is the range line like
+// `{{range $o := orders where status == "open" sortBy date desc limit 100}}`,
+// the clauses are optional, so one collection could feed several sections.
+type rangeClause struct {
+	name   string
+	key    string
+	where  *Parse
+	sortBy []sortField
+	limit  string
+}
+
+type sortField struct {
+	path string
+	desc bool
+}
+
+// newRangeClause parses the clauses after range key.
+func newRangeClause(name, key, clauses string) (*rangeClause, error) {
+	rc := &rangeClause{name: name, key: key}
+	for kw, v := range splitClauses(clauses) {
+		switch kw {
+		case "where":
+			p, err := NewParse("{{" + v + "}}")
+			if err != nil {
+				return nil, fmt.Errorf("Range where `%s`: %v", v, err)
+			}
+			rc.where = p
+		case "sortBy":
+			for _, f := range strings.Split(v, ",") {
+				fs := strings.Fields(f)
+				if len(fs) == 0 || len(fs) > 2 || len(fs) == 2 && fs[1] != "asc" && fs[1] != "desc" {
+					return nil, fmt.Errorf("Range sortBy `%s` should be `field [asc|desc], ...`.", v)
+				}
+				rc.sortBy = append(rc.sortBy, sortField{path: fs[0], desc: len(fs) == 2 && fs[1] == "desc"})
+			}
+		case "limit":
+			rc.limit = v
+		default:
+			return nil, fmt.Errorf("Range clause `%s` should begin with one of %v.", v, rangeKeywords)
+		}
+	}
+	return rc, nil
+}
+
+// splitClauses splits s by the keywords out of quotes.
+func splitClauses(s string) map[string]string {
+	var (
+		result  = make(map[string]string)
+		kw      string
+		inQuote bool
+		sb      strings.Builder
+	)
+	flush := func() {
+		if v := strings.TrimSpace(sb.String()); v != "" || kw != "" {
+			result[kw] = v
+		}
+		sb.Reset()
+	}
+	for _, w := range strings.SplitAfter(s, " ") {
+		if !inQuote && isRangeKeyword(strings.TrimSpace(w)) {
+			flush()
+			kw = strings.TrimSpace(w)
+			continue
+		}
+		if (strings.Count(w, `"`)-strings.Count(w, `\"`))%2 == 1 {
+			inQuote = !inQuote
+		}
+		sb.WriteString(w)
+	}
+	flush()
+	return result
+}
+
+func isRangeKeyword(s string) bool {
+	for _, kw := range rangeKeywords {
+		if s == kw {
+			return true
+		}
+	}
+	return false
+}
+
+// hasClause reports whether the items should be filtered, sorted or limited.
+func (rc *rangeClause) hasClause() bool {
+	return rc.where != nil || len(rc.sortBy) > 0 || rc.limit != ""
+}
+
+// apply filters, sorts and limits the items, the clauses of item are
+// evaluated with the data returned by scope.
+func (rc *rangeClause) apply(m *Xlsxt, items []map[string]interface{}, scope func(item map[string]interface{}) map[string]interface{}) ([]map[string]interface{}, error) {
+	if rc.where != nil {
+		var filtered []map[string]interface{}
+		for _, item := range items {
+			v, err := rc.where.value(m.ctx, scope(item))
+			if err != nil {
+				return nil, err
+			}
+			if truthy(v) {
+				filtered = append(filtered, item)
+			}
+		}
+		items = filtered
+	}
+
+	if len(rc.sortBy) > 0 {
+		keys := make([][]interface{}, len(items))
+		for i, item := range items {
+			data := scope(item)
+			for _, sf := range rc.sortBy {
+				v, err := lookupPath(data, sf.path)
+				if err != nil {
+					return nil, err
+				}
+				keys[i] = append(keys[i], v)
+			}
+		}
+		sort.Stable(itemSorter{items: items, keys: keys, fields: rc.sortBy})
+	}
+
+	if rc.limit != "" {
+		limit, err := rc.limitOf(m.curSheetData)
+		if err != nil {
+			return nil, err
+		}
+		if limit >= 0 && limit < len(items) {
+			items = items[:limit]
+		}
+	}
+	return items, nil
+}
+
+// itemSorter sorts items by their keys of sort fields.
+type itemSorter struct {
+	items  []map[string]interface{}
+	keys   [][]interface{}
+	fields []sortField
+}
+
+func (s itemSorter) Len() int { return len(s.items) }
+
+func (s itemSorter) Swap(i, j int) {
+	s.items[i], s.items[j] = s.items[j], s.items[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
+
+func (s itemSorter) Less(i, j int) bool {
+	for k, f := range s.fields {
+		a, b := s.keys[i][k], s.keys[j][k]
+		if compare("==", a, b) {
+			continue
+		}
+		return compare("<", a, b) != f.desc
+	}
+	return false
+}
+
+// limitOf returns the limit, which could be a number or a key.
+func (rc *rangeClause) limitOf(data map[string]interface{}) (int, error) {
+	t, v := literal(rc.limit)
+	if t == key {
+		var err error
+		if v, err = lookupPath(data, rc.limit); err != nil {
+			return 0, err
+		}
+	}
+	f, _, err := toNumber(v)
+	if err != nil {
+		return 0, fmt.Errorf("Range limit `%s`: %v", rc.limit, err)
+	}
+	return int(f), nil
+}
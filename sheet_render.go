@@ -0,0 +1,770 @@
+package xlsxt
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/360EntSecGroup-Skylar/excelize/v2"
+)
+
+// sheetRender holds the state of the rendering sheet,
+// which will be written after the rows are flushed.
+type sheetRender struct {
+	name        string
+	tplLinks    map[string]*cellLink
+	tplComments map[string]*cellComment
+	links       []axisLink
+	comments    []axisComment
+
+	// template row => rendered rows
+	rows    map[int][]int
+	tplRows int
+	outRows int
+	// template row of range line => template row of end line
+	ranges map[int]int
+
+	validations []*excelize.DataValidation
+	dropdowns   []*axisDropdown
+	lists       *listSheet
+	condFormats []condFormat
+	// rows which begin a new page
+	breaks []int
+	// row => outline level, depth is the level of rendering group
+	outlines map[int]uint8
+	depth    uint8
+	// partials being included, their rows aren't mapped to template
+	includes []*including
+	// merged cells, hcell and vcell
+	merges [][2]string
+}
+
+// condFormat is a conditional format added by Xlsxt.SetConditionalFormat.
+type condFormat struct {
+	area      string
+	formatSet string
+}
+
+type axisLink struct {
+	axis string
+	link *cellLink
+}
+
+type axisComment struct {
+	axis    string
+	comment *cellComment
+}
+
+// axisDropdown is a dropdown list shared by cells with same options.
+type axisDropdown struct {
+	options []string
+	axes    []string
+}
+
+func (m *Xlsxt) newSheetRender(sn string) (*sheetRender, error) {
+	sr := &sheetRender{
+		name:        sn,
+		tplLinks:    make(map[string]*cellLink),
+		tplComments: make(map[string]*cellComment),
+		rows:        make(map[int][]int),
+		ranges:      make(map[int]int),
+		outlines:    make(map[int]uint8),
+		lists:       m.lists,
+	}
+	for _, cf := range m.condFormats[sn] {
+		p, err := NewParse(cf.formatSet)
+		if err != nil {
+			return nil, err
+		}
+		v, err := p.Exec(m.ctx, m.sheetData)
+		if err != nil {
+			return nil, err
+		}
+		sr.condFormats = append(sr.condFormats, condFormat{area: cf.area, formatSet: toString(v)})
+	}
+	for _, c := range m.file.GetComments()[sn] {
+		// text of comment begins with author
+		text := strings.TrimPrefix(strings.TrimPrefix(c.Text, c.Author), ":")
+		sr.tplComments[c.Ref] = &cellComment{Author: c.Author, Text: text}
+	}
+
+	ws := m.file.Sheet[worksheetPath(m.file, sn)]
+	if ws == nil {
+		return sr, nil
+	}
+	if ws.Hyperlinks != nil {
+		for _, hl := range ws.Hyperlinks.Hyperlink {
+			// only the top left cell of the ref will keep the link
+			axis := strings.Split(hl.Ref, ":")[0]
+			_, target, err := m.file.GetCellHyperLink(sn, axis)
+			if err != nil {
+				return nil, err
+			}
+			l := &cellLink{target: target, linkType: linkLocation}
+			if hl.RID != "" {
+				l.linkType = linkExternal
+			}
+			sr.tplLinks[axis] = l
+		}
+	}
+	if ws.DataValidations != nil {
+		for _, dv := range ws.DataValidations.DataValidation {
+			v := *dv
+			// Formula1 and Formula2 both hold the inner xml.
+			v.Formula2 = ""
+			sr.validations = append(sr.validations, &v)
+		}
+	}
+	return sr, nil
+}
+
+// mapRow records the template row is rendered to row.
+func (sr *sheetRender) mapRow(tplRow, row int) {
+	if sr.inPartial() {
+		inc := sr.includes[len(sr.includes)-1]
+		inc.rows[tplRow] = append(inc.rows[tplRow], row)
+		return
+	}
+	sr.rows[tplRow] = append(sr.rows[tplRow], row)
+}
+
+// addRange records the range line and its end line in template.
+func (sr *sheetRender) addRange(begin, end int) {
+	if !sr.inPartial() {
+		sr.ranges[begin] = end
+	}
+}
+
+// inPartial reports whether the rendering rows are included from partial.
+func (sr *sheetRender) inPartial() bool {
+	return len(sr.includes) > 0
+}
+
+// addMerges adds the merged cells of partial to the rendered rows, the merged
+// cells in range are added for each item.
+func (sr *sheetRender) addMerges(inc *including) error {
+	for _, mc := range inc.merges {
+		hcol, hrow, err := excelize.CellNameToCoordinates(mc.GetStartAxis())
+		if err != nil {
+			return err
+		}
+		vcol, vrow, err := excelize.CellNameToCoordinates(mc.GetEndAxis())
+		if err != nil {
+			return err
+		}
+		tops, bottoms := inc.rows[hrow], inc.rows[vrow]
+		for i := 0; i < len(tops) && i < len(bottoms); i++ {
+			hcell, _ := excelize.CoordinatesToCellName(hcol, tops[i])
+			vcell, _ := excelize.CoordinatesToCellName(vcol, bottoms[i])
+			sr.merges = append(sr.merges, [2]string{hcell, vcell})
+		}
+	}
+	return nil
+}
+
+// setOutline sets the outline level of row to the depth of rendering group,
+// the deepest level is kept.
+func (sr *sheetRender) setOutline(row int) {
+	if sr.outlines[row] < sr.depth {
+		sr.outlines[row] = sr.depth
+	}
+}
+
+func (sr *sheetRender) addDropdown(axis string, options []string) {
+	for _, d := range sr.dropdowns {
+		if equalStrings(d.options, options) {
+			d.axes = append(d.axes, axis)
+			return
+		}
+	}
+	sr.dropdowns = append(sr.dropdowns, &axisDropdown{options: options, axes: []string{axis}})
+}
+
+func (sr *sheetRender) writeTo(f *excelize.File) (err error) {
+	for _, al := range sr.links {
+		if err = f.SetCellHyperLink(sr.name, al.axis, al.link.target, al.link.linkType); err != nil {
+			return
+		}
+	}
+	for _, ac := range sr.comments {
+		bs, _ := json.Marshal(ac.comment)
+		if err = f.AddComment(sr.name, ac.axis, string(bs)); err != nil {
+			return
+		}
+	}
+	for _, dv := range sr.validations {
+		if dv.Sqref = sr.mapSqref(dv.Sqref); dv.Sqref == "" {
+			continue
+		}
+		if err = f.AddDataValidation(sr.name, dv); err != nil {
+			return
+		}
+	}
+	for _, cf := range sr.condFormats {
+		if area := sr.mapSqref(cf.area); area != "" {
+			if err = f.SetConditionalFormat(sr.name, area, cf.formatSet); err != nil {
+				return
+			}
+		}
+	}
+	if len(sr.outlines) > 0 {
+		var max uint8
+		for row, level := range sr.outlines {
+			if err = f.SetRowOutlineLevel(sr.name, row, level); err != nil {
+				return
+			}
+			if level > max {
+				max = level
+			}
+		}
+		if ws := f.Sheet[worksheetPath(f, sr.name)]; ws != nil && ws.SheetFormatPr != nil {
+			ws.SheetFormatPr.OutlineLevelRow = max
+		}
+	}
+	for _, mc := range sr.merges {
+		if err = f.MergeCell(sr.name, mc[0], mc[1]); err != nil {
+			return
+		}
+	}
+	for _, row := range sr.breaks {
+		if err = f.InsertPageBreak(sr.name, "A"+strconv.Itoa(row)); err != nil {
+			return
+		}
+	}
+	for _, d := range sr.dropdowns {
+		var formula string
+		if formula, err = sr.lists.formula(d.options); err != nil {
+			return
+		}
+		dv := excelize.NewDataValidation(true)
+		dv.Sqref = strings.Join(d.axes, " ")
+		if err = dv.SetSqrefDropList(formula, true); err != nil {
+			return
+		}
+		if err = f.AddDataValidation(sr.name, dv); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// copyConditionalFormats copies conditional formats in template to result,
+// the sqref is expanded and the relative rows in formula are shifted.
+func (sr *sheetRender) copyConditionalFormats(tpl, f *excelize.File) error {
+	ws := tpl.Sheet[worksheetPath(tpl, sr.name)]
+	if ws == nil || len(ws.ConditionalFormatting) == 0 {
+		return nil
+	}
+	if err := loadWorksheet(f, sr.name); err != nil {
+		return err
+	}
+	out := f.Sheet[worksheetPath(f, sr.name)]
+	for _, cf := range ws.ConditionalFormatting {
+		c := *cf
+		if c.SQRef = sr.mapSqref(cf.SQRef); c.SQRef == "" {
+			continue
+		}
+		delta := firstRow(c.SQRef) - firstRow(cf.SQRef)
+		c.CfRule = cf.CfRule[:0:0]
+		for _, rule := range cf.CfRule {
+			r := *rule
+			r.Formula = make([]string, 0, len(rule.Formula))
+			for _, formula := range rule.Formula {
+				r.Formula = append(r.Formula, shiftFormulaRows(formula, delta))
+			}
+			c.CfRule = append(c.CfRule, &r)
+		}
+		out.ConditionalFormatting = append(out.ConditionalFormatting, &c)
+	}
+	return nil
+}
+
+// copySheetViews copies the sheet views of template sheet to result, like
+// freeze panes, zoom and gridlines. It must be called before the stream writer
+// is created.
+func (m *Xlsxt) copySheetViews(f *excelize.File, sn string) error {
+	ws := m.file.Sheet[worksheetPath(m.file, sn)]
+	if ws == nil || ws.SheetViews == nil {
+		return nil
+	}
+	if err := loadWorksheet(f, sn); err != nil {
+		return err
+	}
+	sv := *ws.SheetViews
+	f.Sheet[worksheetPath(f, sn)].SheetViews = &sv
+	return nil
+}
+
+// copyAutoFilter copies the auto filter of template sheet to result, the
+// filter over a header row is extended to the last row of the following range.
+func (sr *sheetRender) copyAutoFilter(tpl, f *excelize.File) (err error) {
+	ws := tpl.Sheet[worksheetPath(tpl, sr.name)]
+	if ws == nil || ws.AutoFilter == nil {
+		return nil
+	}
+	hcell, vcell := sr.mapArea(ws.AutoFilter.Ref)
+	if hcell == "" {
+		return nil
+	}
+	if err = loadWorksheet(f, sr.name); err != nil {
+		return
+	}
+	out := f.Sheet[worksheetPath(f, sr.name)]
+	sp := out.SheetPr
+	// AutoFilter adds the hidden defined name and resets the sheet properties
+	if err = f.AutoFilter(sr.name, hcell, vcell, ""); err != nil {
+		return
+	}
+	if sp != nil {
+		sp.FilterMode = true
+		out.SheetPr = sp
+	}
+	af := *ws.AutoFilter
+	af.Ref = out.AutoFilter.Ref
+	out.AutoFilter = &af
+	return
+}
+
+// mapArea maps the area in template to the rendered rows, the area ends
+// before a range line is extended to the last row of the range.
+func (sr *sheetRender) mapArea(ref string) (hcell, vcell string) {
+	cells := strings.Split(ref, ":")
+	hc, hr, err := excelize.CellNameToCoordinates(cells[0])
+	if err != nil {
+		return
+	}
+	vc, vr := hc, hr
+	if len(cells) == 2 {
+		if vc, vr, err = excelize.CellNameToCoordinates(cells[1]); err != nil {
+			return
+		}
+	}
+	if end, in := sr.ranges[vr+1]; in {
+		vr = end - 1
+	}
+	runs := sr.mapRowRange(hr, vr)
+	if len(runs) == 0 {
+		return
+	}
+	hcell, _ = excelize.CoordinatesToCellName(hc, runs[0][0])
+	vcell, _ = excelize.CoordinatesToCellName(vc, runs[len(runs)-1][1])
+	return
+}
+
+// xlsxRelationships is the part of sheet relationships used to find tables.
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxTable is the part of table used to write formulas.
+type xlsxTable struct {
+	ID             int    `xml:"id,attr"`
+	Name           string `xml:"name,attr"`
+	Ref            string `xml:"ref,attr"`
+	HeaderRowCount *int   `xml:"headerRowCount,attr"`
+	TotalsRowCount int    `xml:"totalsRowCount,attr"`
+	TableColumns   struct {
+		TableColumn []struct {
+			Name                    string `xml:"name,attr"`
+			TotalsRowFunction       string `xml:"totalsRowFunction,attr"`
+			TotalsRowFormula        string `xml:"totalsRowFormula"`
+			CalculatedColumnFormula string `xml:"calculatedColumnFormula"`
+		} `xml:"tableColumn"`
+	} `xml:"tableColumns"`
+}
+
+var (
+	tableRefRgx  = regexp.MustCompile(`(<table\b[^>]*?\sref=")[^"]*(")`)
+	tableIDRgx   = regexp.MustCompile(`(<table\b[^>]*?\sid=")[^"]*(")`)
+	filterRefRgx = regexp.MustCompile(`(<autoFilter\b[^>]*?\sref=")[^"]*(")`)
+)
+
+// subtotalFunctions are the function numbers of SUBTOTAL for the totals row,
+// the hidden rows are ignored.
+var subtotalFunctions = map[string]int{
+	"average":   101,
+	"countNums": 102,
+	"count":     103,
+	"max":       104,
+	"min":       105,
+	"stdDev":    107,
+	"sum":       109,
+	"var":       110,
+}
+
+// copyTables copies the tables in template sheet to result, the ref of table
+// grows to cover the rendered rows, and the calculated column formulas and
+// totals row formulas are written to cells.
+func (sr *sheetRender) copyTables(tpl, f *excelize.File) (err error) {
+	ws := tpl.Sheet[worksheetPath(tpl, sr.name)]
+	if ws == nil || ws.TableParts == nil {
+		return nil
+	}
+	sheetXML := worksheetPath(tpl, sr.name)
+	relsXML := "xl/worksheets/_rels/" + strings.TrimPrefix(sheetXML, "xl/worksheets/") + ".rels"
+	var rels xlsxRelationships
+	if err = xml.Unmarshal(tpl.XLSX[relsXML], &rels); err != nil {
+		return
+	}
+	for _, tp := range ws.TableParts.TableParts {
+		for _, rel := range rels.Relationships {
+			if rel.ID != tp.RID {
+				continue
+			}
+			tableXML := strings.Replace(rel.Target, "..", "xl", 1)
+			if err = sr.copyTable(f, tpl.XLSX[tableXML]); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+func (sr *sheetRender) copyTable(f *excelize.File, content []byte) (err error) {
+	var t xlsxTable
+	if err = xml.Unmarshal(content, &t); err != nil {
+		return
+	}
+	hcell, vcell := sr.mapArea(t.Ref)
+	if hcell == "" {
+		return nil
+	}
+	tables := make(map[string]bool)
+	for k := range f.XLSX {
+		tables[k] = true
+	}
+	// AddTable adds the relationships and content types of the table part,
+	// then the part is replaced by the template one.
+	if err = f.AddTable(sr.name, hcell, vcell, ""); err != nil {
+		return
+	}
+	var tableXML string
+	for k := range f.XLSX {
+		if !tables[k] && strings.HasPrefix(k, "xl/tables/") {
+			tableXML = k
+		}
+	}
+	var added xlsxTable
+	if err = xml.Unmarshal(f.XLSX[tableXML], &added); err != nil {
+		return
+	}
+	hc, hr, _ := excelize.CellNameToCoordinates(hcell)
+	vc, vr, _ := excelize.CellNameToCoordinates(strings.Split(added.Ref, ":")[1])
+	filterCell, _ := excelize.CoordinatesToCellName(vc, vr-t.TotalsRowCount)
+	out := string(content)
+	out = tableIDRgx.ReplaceAllString(out, "${1}"+strconv.Itoa(added.ID)+"${2}")
+	out = tableRefRgx.ReplaceAllString(out, "${1}"+added.Ref+"${2}")
+	out = filterRefRgx.ReplaceAllString(out, "${1}"+hcell+":"+filterCell+"${2}")
+	f.XLSX[tableXML] = []byte(out)
+
+	first := hr + 1
+	if t.HeaderRowCount != nil && *t.HeaderRowCount == 0 {
+		first = hr
+	}
+	for i, tc := range t.TableColumns.TableColumn {
+		col := hc + i
+		if tc.CalculatedColumnFormula != "" {
+			for row := first; row <= vr-t.TotalsRowCount; row++ {
+				axis, _ := excelize.CoordinatesToCellName(col, row)
+				if err = f.SetCellFormula(sr.name, axis, tc.CalculatedColumnFormula); err != nil {
+					return
+				}
+			}
+		}
+		if t.TotalsRowCount == 0 {
+			continue
+		}
+		formula := tc.TotalsRowFormula
+		if n, in := subtotalFunctions[tc.TotalsRowFunction]; in {
+			formula = fmt.Sprintf("SUBTOTAL(%d,%s[%s])", n, t.Name, tc.Name)
+		}
+		if formula != "" {
+			axis, _ := excelize.CoordinatesToCellName(col, vr)
+			if err = f.SetCellFormula(sr.name, axis, formula); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// copyPageSetup copies the page setup, margins, print options and
+// header/footer of template sheet to result, header/footer are templates.
+func (m *Xlsxt) copyPageSetup(f *excelize.File, sn string) (err error) {
+	ws := m.file.Sheet[worksheetPath(m.file, sn)]
+	if ws == nil {
+		return nil
+	}
+	if err = loadWorksheet(f, sn); err != nil {
+		return
+	}
+	out := f.Sheet[worksheetPath(f, sn)]
+	if ws.SheetPr != nil && ws.SheetPr.PageSetUpPr != nil {
+		if out.SheetPr == nil {
+			sp := *ws.SheetPr
+			out.SheetPr = &sp
+		}
+		out.SheetPr.PageSetUpPr = ws.SheetPr.PageSetUpPr
+	}
+	if ws.PageSetUp != nil {
+		ps := *ws.PageSetUp
+		// printer settings part is not copied
+		ps.RID = ""
+		out.PageSetUp = &ps
+	}
+	if ws.PageMargins != nil {
+		pm := *ws.PageMargins
+		out.PageMargins = &pm
+	}
+	if ws.PrintOptions != nil {
+		po := *ws.PrintOptions
+		out.PrintOptions = &po
+	}
+	if ws.HeaderFooter != nil {
+		hf := *ws.HeaderFooter
+		for _, s := range []*string{&hf.OddHeader, &hf.OddFooter, &hf.EvenHeader, &hf.EvenFooter, &hf.FirstHeader, &hf.FirstFooter} {
+			if *s, err = m.renderString(*s); err != nil {
+				return
+			}
+		}
+		// pictures in header/footer are not copied
+		hf.DrawingHF = nil
+		out.HeaderFooter = &hf
+	}
+	return
+}
+
+// copyDefinedNames copies defined names in template to result, the references
+// are adjusted to the rendered rows, so print areas and print titles are kept.
+// The names of excluded sheets or referring to them are skipped.
+func (m *Xlsxt) copyDefinedNames(f *excelize.File, excluded map[string]bool) error {
+	for _, dn := range m.file.GetDefinedName() {
+		// added with the auto filter
+		if dn.Name == filterDatabase || excluded[dn.Scope] || refersTo(dn.RefersTo, excluded) {
+			continue
+		}
+		if dn.Scope == "Workbook" {
+			dn.Scope = ""
+		}
+		dn.RefersTo = m.mapFormulaRefs(dn.RefersTo)
+		if err := f.SetDefinedName(&dn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const filterDatabase = "_xlnm._FilterDatabase"
+
+var sheetRefRgx = regexp.MustCompile(`((?:'(?:[^']|'')+'|[\w.]+)!)(\$?[A-Z]{1,3}\$?[0-9]+(?::\$?[A-Z]{1,3}\$?[0-9]+)?|\$?[0-9]+:\$?[0-9]+)`)
+
+// mapFormulaRefs maps the references with sheet name like `Sheet1!$A$1:$B$2`
+// or `Sheet1!$1:$2` in formula to the rendered rows.
+func (m *Xlsxt) mapFormulaRefs(formula string) string {
+	return sheetRefRgx.ReplaceAllStringFunc(formula, func(ref string) string {
+		ms := sheetRefRgx.FindStringSubmatch(ref)
+		sr, in := m.sheets[refSheetName(ms[1])]
+		if !in {
+			return ref
+		}
+		if mapped := sr.mapRangeRef(ms[2]); mapped != "" {
+			return ms[1] + mapped
+		}
+		return ref
+	})
+}
+
+// refersTo reports whether formula refers to any of sheets.
+func refersTo(formula string, sheets map[string]bool) bool {
+	for _, ms := range sheetRefRgx.FindAllStringSubmatch(formula, -1) {
+		if sheets[refSheetName(ms[1])] {
+			return true
+		}
+	}
+	return false
+}
+
+// refSheetName returns the sheet name of reference prefix like `'Other Sheet'!`.
+func refSheetName(prefix string) string {
+	sn := strings.TrimSuffix(prefix, "!")
+	if strings.HasPrefix(sn, "'") {
+		sn = strings.Replace(sn[1:len(sn)-1], "''", "'", -1)
+	}
+	return sn
+}
+
+var rangeRefRgx = regexp.MustCompile(`^(\$?[A-Z]{0,3})(\$?)([0-9]+)$`)
+
+// mapRangeRef maps a single range like `$A$1:$B$2` or `$1:$2` to the rendered
+// rows, the result covers all the rendered rows of the range.
+func (sr *sheetRender) mapRangeRef(ref string) string {
+	parts := strings.Split(ref, ":")
+	ms := make([][]string, 0, len(parts))
+	for _, p := range parts {
+		m := rangeRefRgx.FindStringSubmatch(p)
+		if m == nil {
+			return ""
+		}
+		ms = append(ms, m)
+	}
+	hr, _ := strconv.Atoi(ms[0][3])
+	vr := hr
+	if len(ms) == 2 {
+		vr, _ = strconv.Atoi(ms[1][3])
+	}
+	if hr > vr {
+		hr, vr = vr, hr
+	}
+	runs := sr.mapRowRange(hr, vr)
+	if len(runs) == 0 {
+		return ""
+	}
+	begin, end := runs[0][0], runs[len(runs)-1][1]
+	// single cell may be rendered to several rows
+	if len(ms) == 1 && begin != end {
+		ms = append(ms, ms[0])
+	}
+	result := ms[0][1] + ms[0][2] + strconv.Itoa(begin)
+	if len(ms) == 2 {
+		result += ":" + ms[1][1] + ms[1][2] + strconv.Itoa(end)
+	}
+	return result
+}
+
+// mapSqref maps the template cell references (separated by blank)
+// to the rendered cell references.
+func (sr *sheetRender) mapSqref(sqref string) string {
+	var refs []string
+	for _, ref := range strings.Fields(sqref) {
+		refs = append(refs, sr.mapRef(ref)...)
+	}
+	return strings.Join(refs, " ")
+}
+
+func (sr *sheetRender) mapRef(ref string) []string {
+	cells := strings.Split(ref, ":")
+	hc, hr, err := excelize.CellNameToCoordinates(cells[0])
+	if err != nil {
+		return []string{ref}
+	}
+	vc, vr := hc, hr
+	if len(cells) == 2 {
+		if vc, vr, err = excelize.CellNameToCoordinates(cells[1]); err != nil {
+			return []string{ref}
+		}
+	}
+	if hc > vc {
+		hc, vc = vc, hc
+	}
+	if hr > vr {
+		hr, vr = vr, hr
+	}
+
+	var result []string
+	for _, run := range sr.mapRowRange(hr, vr) {
+		h, _ := excelize.CoordinatesToCellName(hc, run[0])
+		v, _ := excelize.CoordinatesToCellName(vc, run[1])
+		if h == v {
+			result = append(result, h)
+		} else {
+			result = append(result, h+":"+v)
+		}
+	}
+	return result
+}
+
+// mapRowRange returns the continuous rendered row runs of template rows [from, to].
+// Rows after the last template row are shifted.
+func (sr *sheetRender) mapRowRange(from, to int) (runs [][2]int) {
+	var rows []int
+	for r := from; r <= to && r <= sr.tplRows; r++ {
+		rows = append(rows, sr.rows[r]...)
+	}
+	if to > sr.tplRows {
+		delta := sr.outRows - sr.tplRows
+		begin, end := from, to+delta
+		if begin <= sr.tplRows {
+			begin = sr.tplRows + 1
+		}
+		if end > excelize.TotalRows {
+			end = excelize.TotalRows
+		}
+		for r := begin + delta; r <= end; r++ {
+			rows = append(rows, r)
+		}
+	}
+	sort.Ints(rows)
+
+	for _, r := range rows {
+		if l := len(runs); l > 0 && runs[l-1][1]+1 >= r {
+			if r > runs[l-1][1] {
+				runs[l-1][1] = r
+			}
+			continue
+		}
+		runs = append(runs, [2]int{r, r})
+	}
+	return
+}
+
+const (
+	listSheetName = "_xlsxt_lists"
+	// formula of data validation must be 0-255 characters
+	maxInlineListLen = 255
+)
+
+// listSheet is a hidden sheet holds the long dropdown options,
+// it's written after all sheets, so it's the last sheet of result.
+type listSheet struct {
+	refs  map[string]string
+	lists [][]string
+}
+
+// formula returns the dropdown formula of options, options will be written to
+// the hidden sheet when they are too long for inline list.
+func (ls *listSheet) formula(options []string) (string, error) {
+	inline := `"` + strings.Join(options, ",") + `"`
+	if len(inline) <= maxInlineListLen && !strings.ContainsAny(strings.Join(options, ""), `,"`) {
+		return escapeXMLText(inline), nil
+	}
+
+	key := strings.Join(options, "\x00")
+	if ref, in := ls.refs[key]; in {
+		return ref, nil
+	}
+	ls.lists = append(ls.lists, options)
+	col, err := excelize.ColumnNumberToName(len(ls.lists))
+	if err != nil {
+		return "", err
+	}
+	ref := fmt.Sprintf("%s!$%s$1:$%s$%d", listSheetName, col, col, len(options))
+	ls.refs[key] = ref
+	return ref, nil
+}
+
+// writeTo writes the collected options to the hidden sheet.
+func (ls *listSheet) writeTo(f *excelize.File) error {
+	if len(ls.lists) == 0 {
+		return nil
+	}
+	f.NewSheet(listSheetName)
+	if err := f.SetSheetVisible(listSheetName, false); err != nil {
+		return err
+	}
+	for c, options := range ls.lists {
+		for i, o := range options {
+			axis, _ := excelize.CoordinatesToCellName(c+1, i+1)
+			if err := f.SetCellStr(listSheetName, axis, o); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package xlsxt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/360EntSecGroup-Skylar/excelize/v2"
+)
+
+var styleMap = make(map[string]*excelize.Style)
+
+// RegisterStyle registers a named style used by `{{style "name" value}}`,
+// style could be a JSON string or *excelize.Style, see excelize.File.NewStyle.
+func RegisterStyle(name string, style interface{}) error {
+	if _, in := styleMap[name]; in {
+		return fmt.Errorf("Exist `%s` style.", name)
+	}
+	switch v := style.(type) {
+	case string:
+		s := &excelize.Style{}
+		if err := json.Unmarshal([]byte(v), s); err != nil {
+			return err
+		}
+		styleMap[name] = s
+	case *excelize.Style:
+		if v == nil {
+			return errors.New("Style is nil.")
+		}
+		styleMap[name] = v
+	default:
+		return fmt.Errorf("`%s` not a JSON string or *excelize.Style.", name)
+	}
+	return nil
+}
+
+// style sets the named style of cell, blank name means no style,
+// e.g. `{{style "danger" amount}}` or `{{style {{level amount}} amount}}`.
+func style(name string, v *cellValue) *cellValue {
+	c := *v
+	if name != "" {
+		c.style = name
+	}
+	return &c
+}
+
+// styleID returns the style id of the named style in result file.
+func (m *Xlsxt) styleID(name string) (int, error) {
+	if id, in := m.styles[name]; in {
+		return id, nil
+	}
+	s, in := styleMap[name]
+	if !in {
+		return 0, fmt.Errorf("Not style `%s`.", name)
+	}
+	// NewStyle will change the style
+	cs := *s
+	id, err := m.out.NewStyle(&cs)
+	if err != nil {
+		return 0, err
+	}
+	m.styles[name] = id
+	return id, nil
+}
+
+// tplStyle is the cell style id in template or partial file.
+type tplStyle struct {
+	file *excelize.File
+	id   int
+}
+
+// copyStyle copies the cell style id of file f to result file,
+// and returns the style id in result file.
+func (m *Xlsxt) copyStyle(f *excelize.File, id int) (int, error) {
+	k := tplStyle{file: f, id: id}
+	if nid, in := m.tplStyles[k]; in {
+		return nid, nil
+	}
+	src, dst := f.Styles, m.out.Styles
+	if src == nil || src.CellXfs == nil || id <= 0 || id >= len(src.CellXfs.Xf) {
+		return 0, nil
+	}
+	xf := src.CellXfs.Xf[id]
+	// cell style xf of result is the default one
+	xf.XfID = intPtr(0)
+	if xf.FontID != nil && src.Fonts != nil && *xf.FontID < len(src.Fonts.Font) {
+		dst.Fonts.Font = append(dst.Fonts.Font, src.Fonts.Font[*xf.FontID])
+		dst.Fonts.Count = len(dst.Fonts.Font)
+		xf.FontID = intPtr(dst.Fonts.Count - 1)
+	}
+	if xf.FillID != nil && src.Fills != nil && *xf.FillID < len(src.Fills.Fill) {
+		dst.Fills.Fill = append(dst.Fills.Fill, src.Fills.Fill[*xf.FillID])
+		dst.Fills.Count = len(dst.Fills.Fill)
+		xf.FillID = intPtr(dst.Fills.Count - 1)
+	}
+	if xf.BorderID != nil && src.Borders != nil && *xf.BorderID < len(src.Borders.Border) {
+		dst.Borders.Border = append(dst.Borders.Border, src.Borders.Border[*xf.BorderID])
+		dst.Borders.Count = len(dst.Borders.Border)
+		xf.BorderID = intPtr(dst.Borders.Count - 1)
+	}
+	// the custom number format begins with 164
+	if xf.NumFmtID != nil && *xf.NumFmtID >= 164 && src.NumFmts != nil {
+		for _, nf := range src.NumFmts.NumFmt {
+			if nf.NumFmtID != *xf.NumFmtID {
+				continue
+			}
+			if dst.NumFmts == nil {
+				nfs := *src.NumFmts
+				nfs.NumFmt = nil
+				dst.NumFmts = &nfs
+			}
+			nid := 164
+			for _, dnf := range dst.NumFmts.NumFmt {
+				if dnf.NumFmtID >= nid {
+					nid = dnf.NumFmtID + 1
+				}
+			}
+			cnf := *nf
+			cnf.NumFmtID = nid
+			dst.NumFmts.NumFmt = append(dst.NumFmts.NumFmt, &cnf)
+			dst.NumFmts.Count = len(dst.NumFmts.NumFmt)
+			xf.NumFmtID = intPtr(nid)
+			break
+		}
+	}
+	dst.CellXfs.Xf = append(dst.CellXfs.Xf, xf)
+	dst.CellXfs.Count = len(dst.CellXfs.Xf)
+	m.tplStyles[k] = dst.CellXfs.Count - 1
+	return dst.CellXfs.Count - 1, nil
+}
@@ -0,0 +1,59 @@
+package xlsxt
+
+import (
+	"testing"
+
+	"github.com/360EntSecGroup-Skylar/excelize/v2"
+)
+
+func cleanStyles() {
+	styleMap = make(map[string]*excelize.Style)
+}
+
+func TestRegisterStyle(t *testing.T) {
+	tests := []struct {
+		name    string
+		style   interface{}
+		wantErr bool
+	}{
+		{
+			name:  "json string",
+			style: `{"font":{"bold":true,"color":"#FF0000"}}`,
+		},
+		{
+			name:  "style struct",
+			style: &excelize.Style{Font: &excelize.Font{Bold: true}},
+		},
+		{
+			name:    "not json string",
+			style:   `{"font":`,
+			wantErr: true,
+		},
+		{
+			name:    "nil style struct",
+			style:   (*excelize.Style)(nil),
+			wantErr: true,
+		},
+		{
+			name:    "not support type",
+			style:   1,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleanStyles()
+			if err := RegisterStyle("s", tt.style); (err != nil) != tt.wantErr {
+				t.Errorf("RegisterStyle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, in := styleMap["s"]; in == tt.wantErr {
+				t.Errorf("RegisterStyle() registered = %v, want %v", in, !tt.wantErr)
+			}
+		})
+	}
+	cleanStyles()
+	RegisterStyle("s", `{}`)
+	if err := RegisterStyle("s", `{}`); err == nil {
+		t.Error("RegisterStyle() should return error when style exist.")
+	}
+}
@@ -1,6 +1,13 @@
 package xlsxt
 
-import "reflect"
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/360EntSecGroup-Skylar/excelize/v2"
+)
 
 func toStringKeyMap(v interface{}) (map[string]interface{}, error) {
 	if v == nil {
@@ -10,11 +17,19 @@ func toStringKeyMap(v interface{}) (map[string]interface{}, error) {
 		return skm, nil
 	}
 
-	t := reflect.TypeOf(v)
-	if t.Kind() != reflect.Map || t.Key().Kind() != reflect.String {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return make(map[string]interface{}), nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Struct && rv.Type() != typeOfTime {
+		return structToMap(rv), nil
+	}
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
 		return nil, NotStringKeyMapValue
 	}
-	rv := reflect.ValueOf(v)
 
 	skm := make(map[string]interface{})
 	iter := rv.MapRange()
@@ -23,3 +38,118 @@ func toStringKeyMap(v interface{}) (map[string]interface{}, error) {
 	}
 	return skm, nil
 }
+
+// structToMap converts the exported fields of struct to map, the field could
+// be referred by its name or the name in json tag.
+func structToMap(rv reflect.Value) map[string]interface{} {
+	skm := make(map[string]interface{})
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		v := rv.Field(i).Interface()
+		skm[f.Name] = v
+		if name := strings.Split(f.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+			skm[name] = v
+		}
+	}
+	return skm
+}
+
+func toString(v interface{}) string {
+	if cv, ok := v.(*cellValue); ok {
+		v = cv.value
+	}
+	rv, err := interface2AppointType(v, typeOfString)
+	if err != nil {
+		return ""
+	}
+	return rv.String()
+}
+
+// worksheetPath returns the worksheet XML path of sheet,
+// like `xl/worksheets/sheet1.xml`.
+func worksheetPath(f *excelize.File, sheet string) string {
+	rels := f.Relationships["xl/_rels/workbook.xml.rels"]
+	if rels == nil || f.WorkBook == nil {
+		return ""
+	}
+	for _, s := range f.WorkBook.Sheets.Sheet {
+		if s.Name != sheet {
+			continue
+		}
+		for _, rel := range rels.Relationships {
+			if rel.ID != s.ID {
+				continue
+			}
+			pathInfo := strings.Split(rel.Target, "/")
+			if l := len(pathInfo); l > 1 {
+				return "xl/" + strings.Join(pathInfo[l-2:], "/")
+			}
+		}
+	}
+	return ""
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+var xmlTextReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escapeXMLText escapes s to be used as inner xml.
+func escapeXMLText(s string) string {
+	return xmlTextReplacer.Replace(s)
+}
+
+// loadWorksheet makes sure the worksheet of sheet is loaded in f.Sheet.
+func loadWorksheet(f *excelize.File, sheet string) error {
+	return f.GetSheetFormatPr(sheet)
+}
+
+var cellRefRgx = regexp.MustCompile(`(\$?)([A-Z]{1,3})(\$?)([0-9]+)\b`)
+
+// shiftFormulaRows shifts the relative row references in formula by delta,
+// the text in quote and the absolute rows like `A$1` are kept.
+func shiftFormulaRows(formula string, delta int) string {
+	if delta == 0 {
+		return formula
+	}
+	parts := strings.Split(formula, `"`)
+	// the odd parts are in quote
+	for i := 0; i < len(parts); i += 2 {
+		parts[i] = cellRefRgx.ReplaceAllStringFunc(parts[i], func(ref string) string {
+			ms := cellRefRgx.FindStringSubmatch(ref)
+			row, _ := strconv.Atoi(ms[4])
+			if ms[3] == "$" || row+delta < 1 {
+				return ref
+			}
+			return ms[1] + ms[2] + ms[3] + strconv.Itoa(row+delta)
+		})
+	}
+	return strings.Join(parts, `"`)
+}
+
+// firstRow returns the row of the first cell in sqref.
+func firstRow(sqref string) int {
+	ms := cellRefRgx.FindStringSubmatch(sqref)
+	if len(ms) == 0 {
+		return 0
+	}
+	row, _ := strconv.Atoi(ms[4])
+	return row
+}
@@ -65,6 +65,22 @@ func Test_toStringKeyMap(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "test struct",
+			args: args{
+				v: &struct {
+					Name  string `json:"name"`
+					Price int
+					inner int
+				}{Name: "a", Price: 1},
+			},
+			want: map[string]interface{}{
+				"Name":  "a",
+				"name":  "a",
+				"Price": 1,
+			},
+			wantErr: false,
+		},
 		{
 			name: "test string not map[string]interface{}",
 			args: args{